@@ -0,0 +1,180 @@
+package env
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Slices and maps of structs are filled from indexed keys instead of a single value:
+//
+//	type Config struct {
+//		Upstreams []UpstreamConfig      `env:"UPSTREAMS"` // UPSTREAMS_0_HOST, UPSTREAMS_0_PORT, UPSTREAMS_1_HOST...
+//		Tenants   map[string]DbConfig   `env:"TENANTS"`   // TENANTS_ACME_HOST, TENANTS_GLOBEX_HOST...
+//	}
+//
+// Slice elements are read from index 0 until the first index without keys.
+// Map keys are discovered by scanning the source, so it must implement KeyLister.
+
+// isStructSlice reports whether t is a slice of nested structs or pointers to them.
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !hasUnmarshaler(t) && isNestedStruct(t.Elem())
+}
+
+// isStructMap reports whether t is a map of nested structs or pointers to them.
+func isStructMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && !hasUnmarshaler(t) && isNestedStruct(t.Elem())
+}
+
+func isStructCollection(t reflect.Type) bool {
+	return isStructSlice(t) || isStructMap(t)
+}
+
+// structType returns struct type of the nested struct or pointer to it.
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// parseStructSlice fills slice of structs from keys PREFIX_0_*, PREFIX_1_* and so on.
+// Field is left untouched if there are no such keys.
+func (p *parser) parseStructSlice(v reflect.Value, field reflect.StructField, key, path string) {
+	elemType := v.Type().Elem()
+
+	var elems []reflect.Value
+	for i := 0; ; i++ {
+		elemKey := p.naming.join(key, strconv.Itoa(i))
+		var found bool
+		p.inElement(elemKey, func() {
+			found = p.hasEnvValues(structType(elemType), elemKey)
+		})
+		if !found {
+			break
+		}
+
+		elem := reflect.New(elemType).Elem()
+		p.inElement(elemKey, func() {
+			p.parseNested(elem, elemKey, path+"["+strconv.Itoa(i)+"]")
+		})
+		elems = append(elems, elem)
+	}
+
+	if len(elems) == 0 {
+		if field.Tag.Get("required") == "true" && !p.kept(v) {
+			p.fail(path, p.naming.join(key, "0"), "", ErrRequired)
+		}
+		return
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		slice.Index(i).Set(elem)
+	}
+	v.Set(slice)
+}
+
+// parseStructMap fills map of structs from keys PREFIX_<NAME>_*, names are discovered by scanning the source.
+// Field is left untouched if there are no such keys.
+func (p *parser) parseStructMap(v reflect.Value, field reflect.StructField, key, path string) {
+	names := p.mapNames(structType(v.Type().Elem()), key)
+	if len(names) == 0 {
+		if field.Tag.Get("required") == "true" && !p.kept(v) {
+			p.fail(path, p.naming.join(key, "<NAME>"), "", ErrRequired)
+		}
+		return
+	}
+
+	m := reflect.MakeMapWithSize(v.Type(), len(names))
+	for _, name := range names {
+		mapKey := reflect.New(v.Type().Key()).Elem()
+		if err := setFieldValue(mapKey, name, field.Tag); err != nil {
+			p.failField(field, path, p.naming.join(key, name), name, err)
+			continue
+		}
+
+		elem := reflect.New(v.Type().Elem()).Elem()
+		elemKey := p.naming.join(key, name)
+		p.inElement(elemKey, func() {
+			p.parseNested(elem, elemKey, path+"["+name+"]")
+		})
+		m.SetMapIndex(mapKey, elem)
+	}
+	v.Set(m)
+}
+
+// inElement runs fn with `prefix` tags resolved relative to the key of collection element,
+// so elements don't share keys of the nested structs with such tags.
+func (p *parser) inElement(key string, fn func()) {
+	n := p.naming
+	p.naming = n.element(key)
+	defer func() { p.naming = n }()
+	fn()
+}
+
+// mapNames finds names of map entries in keys of the source like PREFIX_<NAME>_<FIELD KEY>.
+// Keys of collections in the entries are matched by prefix: PREFIX_<NAME>_UPS_0_HOST.
+// When several field keys match, the shortest name wins.
+func (p *parser) mapNames(t reflect.Type, prefix string) []string {
+	lister, ok := p.source.(KeyLister)
+	if !ok {
+		return nil
+	}
+
+	var suffixes, collections []string
+	sep := p.naming.sep()
+	p.naming.relative().walkFields(t, "", "", nil, func(field reflect.StructField, key, _ string) bool {
+		if isStructCollection(field.Type) {
+			collections = append(collections, sep+key+sep)
+			return true
+		}
+		suffixes = append(suffixes, sep+key)
+		if isSecret(field) {
+			suffixes = append(suffixes, sep+key+fileSuffix)
+		}
+		return true
+	})
+
+	var names []string
+	for _, key := range lister.Keys() {
+		rest, ok := strings.CutPrefix(key, prefix+sep)
+		if !ok {
+			continue
+		}
+		name := ""
+		for _, suffix := range suffixes {
+			if n, ok := strings.CutSuffix(rest, suffix); ok && n != "" && (name == "" || len(n) < len(name)) {
+				name = n
+			}
+		}
+		for _, collection := range collections {
+			if i := strings.Index(rest, collection); i > 0 && (name == "" || i < len(name)) {
+				name = rest[:i]
+			}
+		}
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// hasCollectionValues reports whether the source has keys of the collection.
+// Keys are found by prefix, so the source must implement KeyLister.
+// Probing keys of the elements like parseStructSlice does could recurse infinitely
+// for types like `type Node struct { Children []Node }`.
+func (p *parser) hasCollectionValues(key string) bool {
+	lister, ok := p.source.(KeyLister)
+	if !ok {
+		return false
+	}
+	for _, sourceKey := range lister.Keys() {
+		if strings.HasPrefix(sourceKey, key+p.naming.sep()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type upstreamConfig struct {
+	Host   string `env:"HOST" required:"true"`
+	Port   int    `env:"PORT" default:"80"`
+	Weight int    `env:"WEIGHT"`
+}
+
+type tenantConfig struct {
+	Host     string         `env:"HOST"`
+	DbHost   string         `env:"DB_HOST"`
+	Password Secret[string] `env:"PASSWORD" secret:"true"`
+}
+
+type collectionsConfig struct {
+	Upstreams []upstreamConfig        `env:"UPSTREAMS"`
+	Backups   []*upstreamConfig       `env:"BACKUPS"`
+	Tenants   map[string]tenantConfig `env:"TENANTS"`
+	Shards    map[int]*upstreamConfig `env:"SHARDS"`
+}
+
+func TestParseConfig_StructCollections(t *testing.T) {
+	t.Run("slice of structs", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"UPSTREAMS_0_HOST":   "a",
+			"UPSTREAMS_0_PORT":   "8080",
+			"UPSTREAMS_1_HOST":   "b",
+			"UPSTREAMS_1_WEIGHT": "2",
+			"BACKUPS_0_HOST":     "c",
+		})
+		require.NoError(t, err)
+
+		require.EqualValues(t, []upstreamConfig{
+			{Host: "a", Port: 8080},
+			{Host: "b", Port: 80, Weight: 2},
+		}, cfg.Upstreams)
+		require.Equal(t, 1, len(cfg.Backups))
+		require.Equal(t, "c", cfg.Backups[0].Host)
+		require.Nil(t, cfg.Tenants)
+	})
+
+	t.Run("gap stops slice", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"UPSTREAMS_0_HOST": "a",
+			"UPSTREAMS_2_HOST": "c",
+		})
+		require.NoError(t, err)
+
+		require.EqualValues(t, []upstreamConfig{{Host: "a", Port: 80}}, cfg.Upstreams)
+	})
+
+	t.Run("element errors have index", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"UPSTREAMS_0_HOST": "a",
+			"UPSTREAMS_1_PORT": "8080",
+		})
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "UPSTREAMS_1_HOST", fieldErr.Key)
+		require.Equal(t, "Upstreams[1].Host", fieldErr.Field)
+		require.ErrorIs(t, err, ErrRequired)
+	})
+
+	t.Run("map of structs", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"TENANTS_ACME_HOST":          "acme.local",
+			"TENANTS_ACME_DB_HOST":       "db.acme.local",
+			"TENANTS_GLOBEX_CORP_HOST":   "globex.local",
+			"TENANTS_INITECH_PASSWORD":   "secret",
+			"SHARDS_1_HOST":              "shard1",
+			"SHARDS_2_PORT":              "9000",
+			"SHARDS_2_HOST":              "shard2",
+			"TENANTS_UNRELATED_SUFFIXES": "ignored",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, 3, len(cfg.Tenants))
+		require.Equal(t, "acme.local", cfg.Tenants["ACME"].Host)
+		require.Equal(t, "db.acme.local", cfg.Tenants["ACME"].DbHost)
+		require.Equal(t, "globex.local", cfg.Tenants["GLOBEX_CORP"].Host)
+		require.Equal(t, "secret", cfg.Tenants["INITECH"].Password.Value())
+
+		require.Equal(t, 2, len(cfg.Shards))
+		require.Equal(t, "shard1", cfg.Shards[1].Host)
+		require.Equal(t, 9000, cfg.Shards[2].Port)
+	})
+
+	t.Run("invalid map key", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, MapSource{"SHARDS_FIRST_HOST": "shard1"})
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "SHARDS_FIRST", fieldErr.Key)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &struct {
+			Upstreams []upstreamConfig        `env:"UPSTREAMS" required:"true"`
+			Tenants   map[string]tenantConfig `env:"TENANTS" required:"true"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 2, len(configErr.Errors))
+		require.Equal(t, "UPSTREAMS_0", configErr.Errors[0].Key)
+		require.Equal(t, "TENANTS_<NAME>", configErr.Errors[1].Key)
+	})
+
+	t.Run("nested collections", func(t *testing.T) {
+		t.Parallel()
+
+		type node struct {
+			Name     string `env:"NAME"`
+			Children []node `env:"CHILDREN"`
+		}
+		cfg := &struct {
+			Root *node `env:"ROOT"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"ROOT_CHILDREN_0_NAME":            "a",
+			"ROOT_CHILDREN_0_CHILDREN_0_NAME": "b",
+		})
+		require.NoError(t, err)
+
+		require.NotNil(t, cfg.Root)
+		require.Equal(t, "a", cfg.Root.Children[0].Name)
+		require.Equal(t, "b", cfg.Root.Children[0].Children[0].Name)
+	})
+
+	t.Run("collections in map entries", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &struct {
+			Tenants map[string]struct {
+				Ups    []upstreamConfig          `env:"UPS"`
+				Shards map[string]upstreamConfig `env:"SHARDS"`
+			} `env:"TENANTS"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"TENANTS_ACME_UPS_0_HOST":           "a",
+			"TENANTS_ACME_UPS_1_HOST":           "b",
+			"TENANTS_GLOBEX_SHARDS_EU_HOST":     "eu",
+			"TENANTS_BIG_CORP_SHARDS_US_HOST":   "us",
+			"TENANTS_BIG_CORP_UPS_0_HOST":       "c",
+			"TENANTS_BIG_CORP_UPS_0_WEIGHT":     "2",
+			"TENANTS_BIG_CORP_SHARDS_US_PORT":   "81",
+			"TENANTS_GLOBEX_SHARDS_EU_WEIGHT":   "1",
+			"TENANTS_GLOBEX_SHARDS_EU_PORT":     "82",
+			"TENANTS_BIG_CORP_SHARDS_US_WEIGHT": "3",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, 3, len(cfg.Tenants))
+		require.Equal(t, 2, len(cfg.Tenants["ACME"].Ups))
+		require.Equal(t, "b", cfg.Tenants["ACME"].Ups[1].Host)
+		require.Equal(t, "eu", cfg.Tenants["GLOBEX"].Shards["EU"].Host)
+		require.Equal(t, 2, cfg.Tenants["BIG_CORP"].Ups[0].Weight)
+		require.Equal(t, 81, cfg.Tenants["BIG_CORP"].Shards["US"].Port)
+	})
+
+	t.Run("source without key listing", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &collectionsConfig{}
+		err := ParseConfigFrom(cfg, lookupOnly{"UPSTREAMS_0_HOST": "a", "TENANTS_ACME_HOST": "acme"})
+		require.NoError(t, err)
+
+		require.Equal(t, 1, len(cfg.Upstreams))
+		require.Nil(t, cfg.Tenants)
+	})
+}
+
+// lookupOnly is a source that doesn't implement KeyLister.
+type lookupOnly map[string]string
+
+func (l lookupOnly) Lookup(key string) (string, bool) {
+	value, ok := l[key]
+	return value, ok
+}
@@ -1,8 +1,9 @@
 package env
 
 import (
-	"os"
+	"encoding"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -13,20 +14,38 @@ import (
 Config should look like this:
 type Config struct {
 	Db DbConfig `env:"DB"`
+	Cache *CacheConfig `env:"CACHE"` // allocated only if some of CACHE_* is set
+	Common CommonConfig `inline:"true"` // fields are read without COMMON_ prefix
 	Kafka KafkaConfig `env:"KAFKA"`
 	Timeout CustomDuration `env:"TIMEOUT" default:"10d"`
 }
 
 type DbConfig struct {
-	Host string `env:"HOST" required:"true"`
-	Port int    `env:"PORT" default:"5432"`
+	Host string `env:"HOST" required:"true" desc:"Database host"` // desc is shown by Describe
+	Port int    `env:"PORT" default:"5432" min:"1" max:"65535"`
+	URL  string `env:"URL"`
+	Pool PoolConfig `env:"POOL"` // nested structs can be arbitrarily deep
+}
+
+type PoolConfig struct {
+	Max int `env:"MAX" default:"10"`
+}
+
+type CacheConfig struct {
+	Addr string `env:"ADDR"`
+}
+
+type CommonConfig struct {
+	LogLevel string `env:"LOG_LEVEL" default:"info"`
 }
 
 type KafkaConfig struct {
-	Brokers []string `env:"BROKERS"` // default sep is comma
+	Brokers []string `env:"BROKERS" validate:"hostport"` // default sep is comma
+	Partitions map[string]int `env:"PARTITIONS" sep:";" kvsep:"="` // default sep is comma, default kvsep is colon
+	Topic   string   `env:"TOPIC" default:"events" notEmpty:"true"` // KAFKA_TOPIC= gets default value
 }
 
-type CustomDuration time.Duration
+type CustomDuration time.Duration // time.Duration is supported out of the box, it's just an example of EnvUnmarshaler
 
 func (d *CustomDuration) UnmarshalEnv(v string) error {
 	if strings.HasSuffix(v, "d") {
@@ -47,105 +66,557 @@ func (d *CustomDuration) UnmarshalEnv(v string) error {
 	return nil
 }
 
+Besides basic kinds, slices and pointers, these types are supported natively:
+time.Duration, time.Time (RFC3339 or layout from `layout:"2006-01-02"` tag),
+url.URL, net.IP, netip.Addr, netip.AddrPort, netip.Prefix, *regexp.Regexp,
+*big.Int, *big.Float and ByteSize (64MiB, 1.5GB).
+
+Value is parsed by the first applicable method:
+ 1. EnvUnmarshaler implemented by the field type
+ 2. parser of supported standard library type
+ 3. encoding.TextUnmarshaler implemented by the field type
+ 4. flag.Value (or any type with Set(string) error method) implemented by the field type
+ 5. parser of the kind: string, ints, uints, floats, bool, slice, map or pointer
+Methods are found for both pointer and value receivers.
+
+Elements of slices and maps are trimmed of whitespace.
+Separators inside elements can be escaped with backslash: `a\,b,c` is ["a,b", "c"].
+
+Final values of the fields are validated by tags (see validateField for the details):
+min, max, oneof, regex and validate (url, port, hostport). Values kept by Load are validated too,
+zero values of unset keys are checked only against min and max.
+After all fields are parsed, Validate method of every struct implementing Validator is called.
+
+Secrets are marked with `secret:"true"` tag or wrapped into Secret[T] type.
+Their values are redacted in errors, descriptions, provenance and watcher changes and can be read from file:
+DB_PASSWORD_FILE=/run/secrets/db is used when DB_PASSWORD is unset.
+The tag doesn't change the field itself, so fmt prints tagged string in clear: {Password:hunter2}.
+Use Secret[T] for values which can be printed with the config.
+
+Values of files (.env, JSON, INI), MapSource and defaults can reference other variables:
+${VAR}, ${VAR:-default}, ${VAR:?error}, $$ is a literal $. Process environment and flags
+are already expanded by the shell, so their values are used as is, see ExpandingSource.
+Secret fields are not expanded at all. `expand:"true"` tag enables expansion for the field
+regardless of the source, `expand:"false"` disables it.
+
+Slices and maps of structs are filled from indexed keys, see collections.go:
+UPSTREAMS_0_HOST, UPSTREAMS_1_HOST for []Upstream and TENANTS_ACME_HOST for map[string]Tenant.
+
+Key of the field is its uppercased `env` tag or field name, `env:"-"` ignores the field.
+Options KeyPrefix, KeySeparator, KeyNaming and `prefix` tag of nested structs change keys, see naming.go:
+ParseConfig(&cfg, env.KeyPrefix("MYSVC_"), env.KeyNaming(env.ScreamingSnake)) reads MaxConns from MYSVC_MAX_CONNS.
+
+FromFiles layers JSON and INI files under env: ParseConfig(&cfg, env.FromFiles("config.json")),
+nested objects and sections are flattened to keys, see filesource.go.
+
+Marshal and WriteDotEnv do the opposite: they format config back into the keys parser reads.
+
+Strict mode rejects variables under the given prefix which don't map to any field:
+ParseConfig(&cfg, env.Strict("MYSVC_")) fails on MYSVC_DB_HSOT and suggests MYSVC_DB_HOST.
+
+WithProvenance option reports where value of every field comes from: env, .env:12, default tag or secret file.
+
+RegisterFlags derives command-line flags from the keys (--db-host for DB_HOST),
+FlagSource placed before OSSource lets flags override env.
+
+Values encrypted with cryptokit.AesCryptor (DB_PASSWORD=enc:<base64>) are decrypted
+with the key from DOTENV_KEY or DOTENV_KEY_FILE, see encrypt.go.
+
+Unset keys get value of the `default` tag, keys without default are left untouched.
+Key set to an empty value counts as set unless field has `notEmpty:"true"` tag,
+so `required:"true"` is satisfied by FOO= and `default` is not applied.
+
 Expected env file:
 DB_HOST=localhost
 DB_PORT=5432
+DB_URL=postgres://${DB_HOST}:${DB_PORT}/app
+DB_POOL_MAX=20
+
+CACHE_ADDR=localhost:6379
+
+LOG_LEVEL=debug
 
 KAFKA_BROKERS=localhost:9092,localhost:9093
+KAFKA_PARTITIONS=events=3;audit=1
 
 TIMEOUT=10d
 */
 
-func ParseConfig(cfg any) error {
+// ParseConfig fills cfg with values from process environment or sources set by FromSources.
+// cfg must be a non-nil pointer to a struct.
+func ParseConfig(cfg any, opts ...Option) error {
+	o := newOptions(opts)
+	if o.sources == nil {
+		o.sources = []Source{OSSource{}}
+	}
+
+	_, err := parseConfig(cfg, o)
+	return err
+}
+
+// ParseConfigFrom fills cfg with values from sources.
+// If several sources have the same key, the first one wins.
+func ParseConfigFrom(cfg any, sources ...Source) error {
+	return ParseConfig(cfg, FromSources(sources...))
+}
+
+type Option func(*options)
+
+type options struct {
+	sources      []Source
+	strict       bool
+	strictPrefix string
+	provenance   *Provenance
+	keepValues   bool
+	naming       naming
+	files        []string
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// FromSources sets sources to read values from instead of process environment.
+// If several sources have the same key, the first one wins.
+func FromSources(sources ...Source) Option {
+	return func(o *options) {
+		o.sources = append([]Source{}, sources...)
+	}
+}
+
+// parseConfig fills cfg with values from sources and returns parser
+// to let callers inspect what was read.
+func parseConfig(cfg any, o options) (*parser, error) {
 	// Get the reflect value and type of the config struct
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
-		return errs.New("config must be a non-nil pointer")
+		return nil, errs.New("config must be a non-nil pointer")
 	}
 
 	v = v.Elem()
 	t := v.Type()
 
 	if t.Kind() != reflect.Struct {
-		return errs.New("config must be a struct")
+		return nil, errs.New("config must be a struct")
+	}
+
+	if o.provenance != nil {
+		*o.provenance = nil
 	}
 
-	// Iterate through all fields in the struct
+	sources := append([]Source{}, o.sources...)
+	for _, path := range o.files {
+		fileSource, err := openFileSource(path, o.naming)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, fileSource)
+	}
+
+	var source Source = ChainSource(sources)
+	var recorder *recordingSource
+	if o.strict {
+		recorder = &recordingSource{source: source, keys: make(map[string]bool)}
+		source = recorder
+	}
+
+	p := &parser{
+		source:     source,
+		decrypter:  &decrypter{lookup: source.Lookup},
+		provenance: o.provenance,
+		keepValues: o.keepValues,
+		naming:     o.naming,
+		files:      append([]string{}, o.files...),
+	}
+	p.expander = expander{lookup: p.lookupDecrypted}
+	p.parseStruct(v, "", "")
+	if o.strict {
+		p.checkUnknownKeys(o.strictPrefix, recorder)
+	}
+	if len(p.errors) == 0 {
+		p.runValidators()
+	}
+	if len(p.errors) > 0 {
+		return p, &ConfigError{Errors: p.errors}
+	}
+	return p, nil
+}
+
+// parser walks the config struct and collects errors of all fields
+// instead of stopping at the first one.
+type parser struct {
+	source     Source
+	expander   expander
+	decrypter  *decrypter
+	errors     []*FieldError
+	validators []structRef
+	files      []string    // config files and value files of secrets, which were read
+	keepValues bool        // non-zero fields are kept instead of applying `default` tag
+	provenance *Provenance // origins of the values, nil if not requested
+	naming     naming
+}
+
+// parseStruct fills every exported field of struct v.
+// Keys of the fields are joined to prefix, empty prefix means root struct.
+// path is the Go path of v (Db.Pool), used in error reports.
+func (p *parser) parseStruct(v reflect.Value, prefix, path string) {
+	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		fieldValue := v.Field(i)
+		if isIgnored(field) {
+			continue
+		}
+
+		p.parseField(v.Field(i), field, prefix, joinPath(path, field.Name))
+	}
 
-		if err := parseField(fieldValue, field); err != nil {
-			return errs.Wrapf(err, "failed to parse field %s", field.Name)
+	if reflect.PointerTo(t).Implements(validatorType) {
+		p.validators = append(p.validators, structRef{value: v, prefix: prefix, path: path})
+	}
+}
+
+func (p *parser) parseField(v reflect.Value, field reflect.StructField, prefix, path string) {
+	// Handle struct fields recursively
+	if isNestedStruct(v.Type()) {
+		p.parseNested(v, p.naming.nestedKey(field, prefix), path)
+		return
+	}
+
+	key := p.naming.fieldKey(field, prefix)
+
+	// Handle slices and maps of structs, filled from indexed keys
+	if isStructSlice(v.Type()) {
+		p.parseStructSlice(v, field, key, path)
+		return
+	}
+	if isStructMap(v.Type()) {
+		p.parseStructMap(v, field, key, path)
+		return
+	}
+
+	// Unsupported types are reported even if the key is unset
+	if t := unwrapSecret(v).Type(); !isSupportedType(t) {
+		p.failField(field, path, key, "", errs.Newf("%w %s", ErrUnsupportedType, t))
+		return
+	}
+
+	useDefault := !p.kept(v)
+	envValue, ok, err := p.getEnvValue(key, field, useDefault)
+	if err != nil {
+		p.failField(field, path, key, envValue, err)
+		return
+	}
+	if p.provenance != nil {
+		p.recordOrigin(field, key, path, envValue, ok)
+	}
+
+	// Handle non-struct fields
+	if ok {
+		if err := setFieldValue(v, envValue, field.Tag); err != nil {
+			p.failField(field, path, key, envValue, err)
+			return
 		}
 	}
 
-	return nil
+	// The final value is validated: it's read from the key, kept by Load or zero if the key is unset
+	if err := p.validateValue(v, field, envValue, ok); err != nil {
+		p.failField(field, path, key, envValue, err)
+	}
 }
 
-func parseField(v reflect.Value, field reflect.StructField) error {
-	prefix := field.Tag.Get("env")
-	if prefix == "" {
-		prefix = field.Name
+// validateValue validates final value of the field, see validateField.
+// Values which weren't read from the key are formatted for checks of raw values,
+// zero values of unset keys are checked only against bounds.
+func (p *parser) validateValue(v reflect.Value, field reflect.StructField, raw string, ok bool) error {
+	if ok {
+		return validateField(v, raw, field.Tag)
+	}
+	if v.IsZero() {
+		return validateRange(unwrapSecret(v), field.Tag)
 	}
+	raw, err := formatValue(v, field.Tag)
+	if err != nil {
+		return err
+	}
+	return validateField(v, raw, field.Tag)
+}
 
-	t := v.Type()
+// kept reports whether v already holds a value which must be kept instead of applying defaults, see Load.
+func (p *parser) kept(v reflect.Value) bool {
+	return p.keepValues && !v.IsZero()
+}
 
-	// Handle struct fields recursively
-	if t.Kind() == reflect.Struct {
-		for i := 0; i < t.NumField(); i++ {
-			field := t.Field(i)
-			fieldValue := v.Field(i)
-
-			envKey := prefix + "_" + field.Tag.Get("env")
-			if field.Tag.Get("env") == "" {
-				envKey = prefix + "_" + field.Name
-			}
+// parseNested fills struct or pointer to struct.
+// Nil pointer is allocated only if env has at least one key of the nested struct,
+// so optional sections stay nil together with their required fields.
+func (p *parser) parseNested(v reflect.Value, prefix, path string) {
+	if v.Kind() != reflect.Ptr {
+		p.parseStruct(v, prefix, path)
+		return
+	}
+
+	if !v.IsNil() {
+		p.parseStruct(v.Elem(), prefix, path)
+		return
+	}
+
+	if !p.hasEnvValues(v.Type().Elem(), prefix) {
+		return
+	}
+
+	nested := reflect.New(v.Type().Elem())
+	p.parseStruct(nested.Elem(), prefix, path)
+	v.Set(nested)
+}
+
+// failField reports failure of the field, hiding value of secret fields.
+func (p *parser) failField(field reflect.StructField, path, key, value string, err error) {
+	if isSecret(field) && value != "" {
+		err = &redactedError{err: err, secret: value}
+		value = redacted
+	}
+	p.fail(path, key, value, err)
+}
+
+func (p *parser) fail(path, key, value string, err error) {
+	p.errors = append(p.errors, &FieldError{
+		Field: path,
+		Key:   key,
+		Value: value,
+		Err:   err,
+	})
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// hasEnvValues reports whether any key of struct type t is present in the source.
+func (p *parser) hasEnvValues(t reflect.Type, prefix string) bool {
+	finished := p.naming.walkFields(t, prefix, "", nil, func(field reflect.StructField, key, _ string) bool {
+		if isStructCollection(field.Type) {
+			return !p.hasCollectionValues(key)
+		}
+		_, ok, _ := p.lookup(key, field)
+		return !ok
+	})
+	return !finished
+}
+
+// walkFields calls visit for every field of struct type t that is parsed from a single key
+// or is a collection of structs, descending into nested structs the same way parser does.
+// Walk stops when visit returns false, the result reports whether all fields were visited.
+// visited guards against self-referencing types like `type Node struct { Next *Node }`.
+func (n naming) walkFields(
+	t reflect.Type, prefix, path string, visited []reflect.Type,
+	visit func(field reflect.StructField, key, path string) bool,
+) bool {
+	if slices.Contains(visited, t) {
+		return true
+	}
+	visited = append(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isIgnored(field) {
+			continue
+		}
 
-			envValue, err := getEnvValue(envKey, field.Tag)
-			if err != nil {
-				return errs.Wrapf(err, "failed to get env value for field %s", field.Name)
+		fieldPath := joinPath(path, field.Name)
+		if isNestedStruct(field.Type) {
+			if !n.walkFields(structType(field.Type), n.nestedKey(field, prefix), fieldPath, visited, visit) {
+				return false
 			}
+			continue
+		}
 
-			if err := setFieldValue(fieldValue, envValue); err != nil {
-				return errs.Wrapf(err, "failed to set field %s", field.Name)
+		if !visit(field, n.fieldKey(field, prefix), fieldPath) {
+			return false
+		}
+	}
+	return true
+}
+
+// isNestedStruct reports whether values of type t should be filled field by field
+// rather than parsed from a single env value.
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || hasTypeParser(t) {
+		return false
+	}
+	return !hasUnmarshaler(t)
+}
+
+// lookup returns value of the key and reports whether the key is present.
+// References to other variables are expanded if both the source and the field allow it, see p.expands.
+// Values of secret fields can be read from file at path of KEY_FILE variable, such values are not expanded.
+// Empty value counts as unset for fields with `notEmpty:"true"` tag.
+// Lists of ListSource are joined with `sep` of the field.
+// On error the value of the source is returned, so it can be redacted.
+func (p *parser) lookup(key string, field reflect.StructField) (string, bool, error) {
+	raw, ok := p.source.Lookup(key)
+	if elems, isList := sourceList(p.source, key); ok && isList {
+		raw = joinEscaped(elems, tagOrDefault(field.Tag, "sep", defaultSep))
+	}
+	value := raw
+	if ok && strings.HasPrefix(value, encryptedPrefix) {
+		var err error
+		if value, err = p.decrypter.decrypt(value); err != nil {
+			return raw, true, errs.Wrap(err, "failed to decrypt value")
+		}
+	}
+	if ok && p.expands(key, field) {
+		expanded, err := p.expander.expand(key, value)
+		if err != nil {
+			if isSecret(field) && value != raw {
+				err = &redactedError{err: err, secret: value}
 			}
+			return raw, true, err
 		}
-		return nil
+		value = expanded
 	}
+	if isSecret(field) {
+		if path, fileOk := p.source.Lookup(key + fileSuffix); fileOk {
+			if ok {
+				return "", true, errs.Newf("both %s and %s%s are set", key, key, fileSuffix)
+			}
+			p.files = append(p.files, path)
+			var err error
+			if value, err = readValueFile(path); err != nil {
+				return "", true, err
+			}
+			ok = true
+		}
+	}
+	if ok && value == "" && field.Tag.Get("notEmpty") == "true" {
+		return "", false, nil
+	}
+	return value, ok, nil
+}
 
-	envValue, err := getEnvValue(prefix, field.Tag)
-	if err != nil {
-		return errs.Wrapf(err, "failed to get env value for field %s", field.Name)
+// expands reports whether value of the key should be expanded: field must allow it, see fieldExpands,
+// and the source must be ExpandingSource unless field has `expand:"true"` tag.
+func (p *parser) expands(key string, field reflect.StructField) bool {
+	if field.Tag.Get("expand") == "true" {
+		return true
 	}
+	return fieldExpands(field) && sourceExpands(p.source, key)
+}
 
-	// Handle non-struct fields
-	return setFieldValue(v, envValue)
+// lookupDecrypted is lookup of the expander, encrypted values are decrypted.
+// Values which can't be decrypted are returned as is, error is reported by the field which reads them.
+// Values of sources which don't expand are escaped, so they are referenced literally.
+func (p *parser) lookupDecrypted(key string) (string, bool) {
+	value, ok := p.source.Lookup(key)
+	if ok && strings.HasPrefix(value, encryptedPrefix) {
+		if decrypted, err := p.decrypter.decrypt(value); err == nil {
+			value = decrypted
+		}
+	}
+	if ok && !sourceExpands(p.source, key) {
+		value = strings.ReplaceAll(value, "$", "$$")
+	}
+	return value, ok
 }
 
-func getEnvValue(key string, tag reflect.StructTag) (string, error) {
-	envValue := os.Getenv(strings.ToUpper(key))
-	if envValue == "" {
-		if defaultVal := tag.Get("default"); defaultVal != "" {
-			envValue = defaultVal
+// getEnvValue returns value of the key or value of the `default` tag if the key is unset.
+// ok is false when there is neither, so the field should be left untouched.
+// useDefault is false when the field already holds a value which must be kept, see Load,
+// such value satisfies `required` tag.
+func (p *parser) getEnvValue(key string, field reflect.StructField, useDefault bool) (value string, ok bool, err error) {
+	envValue, ok, err := p.lookup(key, field)
+	if err != nil || ok {
+		return envValue, ok, err
+	}
+	if defaultVal, ok := field.Tag.Lookup("default"); ok && useDefault {
+		if !fieldExpands(field) {
+			return defaultVal, true, nil
 		}
+		expanded, err := p.expander.expand(key, defaultVal)
+		if err != nil {
+			return defaultVal, true, err
+		}
+		return expanded, true, nil
 	}
-	if envValue == "" && tag.Get("required") == "true" {
-		return "", errs.Newf("required environment variable %s is not set", key)
+	if field.Tag.Get("required") == "true" && useDefault {
+		return "", false, ErrRequired
 	}
-	return envValue, nil
+	return "", false, nil
 }
 
 type EnvUnmarshaler interface {
 	UnmarshalEnv(string) error
 }
 
-func setFieldValue(v reflect.Value, envValue string) error {
+// valueSetter is implemented by flag.Value and similar types.
+type valueSetter interface {
+	Set(string) error
+}
+
+var (
+	envUnmarshalerType  = reflect.TypeFor[EnvUnmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	valueSetterType     = reflect.TypeFor[valueSetter]()
+)
+
+// hasUnmarshaler reports whether t parses itself from string with pointer or value receiver.
+func hasUnmarshaler(t reflect.Type) bool {
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(secretHolderType) ||
+		ptr.Implements(envUnmarshalerType) ||
+		ptr.Implements(textUnmarshalerType) ||
+		ptr.Implements(valueSetterType)
+}
+
+// isSupportedType reports whether setFieldValue can parse values of type t.
+func isSupportedType(t reflect.Type) bool {
+	if hasUnmarshaler(t) || hasTypeParser(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice, reflect.Ptr:
+		return isSupportedType(t.Elem())
+	case reflect.Map:
+		return isSupportedType(t.Key()) && isSupportedType(t.Elem())
+	}
+	return false
+}
+
+func setFieldValue(v reflect.Value, envValue string, tag reflect.StructTag) error {
+	v = unwrapSecret(v)
+
 	// Check if the field implements UnmarshalEnv
 	if unmarshaler, ok := v.Addr().Interface().(EnvUnmarshaler); ok {
 		return unmarshaler.UnmarshalEnv(envValue)
 	}
 
+	// Handle standard library types like time.Duration or *url.URL
+	if ok, err := setKnownType(v, envValue, tag); ok {
+		return err
+	}
+
+	// Fall back to generic unmarshalers, implemented by slog.Level, netip.Addr and many enums
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(envValue))
+	}
+	if setter, ok := v.Addr().Interface().(valueSetter); ok {
+		return setter.Set(envValue)
+	}
+
 	// Handle different types
 	switch v.Kind() {
 	case reflect.String:
@@ -180,17 +651,58 @@ func setFieldValue(v reflect.Value, envValue string) error {
 		v.SetBool(val)
 
 	case reflect.Slice:
-		parts := strings.Split(envValue, ",")
+		if envValue == "" {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+			return nil
+		}
+		parts := splitElements(envValue, tag)
 		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
 		for i, part := range parts {
-			if err := setFieldValue(slice.Index(i), part); err != nil {
-				return err
+			if err := setFieldValue(slice.Index(i), part, tag); err != nil {
+				return errs.Wrapf(err, "failed to parse element %d", i)
 			}
 		}
 		v.Set(slice)
 
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		if envValue == "" {
+			v.Set(m)
+			return nil
+		}
+		sep := tagOrDefault(tag, "sep", defaultSep)
+		kvSep := tagOrDefault(tag, "kvsep", defaultKVSep)
+		for _, entry := range splitEscaped(envValue, sep) {
+			rawKey, rawValue, ok := cutEscaped(entry, kvSep)
+			if !ok {
+				return errs.Newf("invalid map entry %q, expected key%svalue", strings.TrimSpace(entry), kvSep)
+			}
+
+			key := reflect.New(v.Type().Key()).Elem()
+			rawKey = unescape(strings.TrimSpace(rawKey), sep, kvSep)
+			if err := setFieldValue(key, rawKey, tag); err != nil {
+				return errs.Wrapf(err, "failed to parse key %q", rawKey)
+			}
+
+			value := reflect.New(v.Type().Elem()).Elem()
+			rawValue = unescape(strings.TrimSpace(rawValue), sep, kvSep)
+			if err := setFieldValue(value, rawValue, tag); err != nil {
+				return errs.Wrapf(err, "failed to parse value of key %q", rawKey)
+			}
+
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setFieldValue(elem.Elem(), envValue, tag); err != nil {
+			return err
+		}
+		v.Set(elem)
+
 	default:
-		return errs.Newf("unsupported type %s", v.Kind())
+		return errs.Newf("%w %s", ErrUnsupportedType, v.Type())
 	}
 
 	return nil
@@ -1,6 +1,8 @@
 package env
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
@@ -76,7 +78,12 @@ func TestParseConfig(t *testing.T) {
 		cfg := &testConfig{}
 		err := ParseConfig(cfg)
 		require.Error(t, err)
-		require.True(t, strings.HasSuffix(err.Error(), "DB_HOST is not set"))
+		require.ErrorIs(t, err, ErrRequired)
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "DB_HOST", fieldErr.Key)
+		require.Equal(t, "Db.Host", fieldErr.Field)
 	})
 
 	t.Run("default values", func(t *testing.T) {
@@ -189,6 +196,497 @@ func TestParseConfig(t *testing.T) {
 	})
 }
 
+type appConfig struct {
+	Storage StorageConfig `env:"STORAGE"`
+	Cache   *CacheConfig  `env:"CACHE"`
+	Common  CommonConfig  `inline:"true"`
+}
+
+type StorageConfig struct {
+	Postgres *PostgresConfig `env:"POSTGRES"`
+}
+
+type PostgresConfig struct {
+	Host string     `env:"HOST" required:"true"`
+	Pool PoolConfig `env:"POOL"`
+}
+
+type PoolConfig struct {
+	Max int `env:"MAX" default:"10"`
+}
+
+type CacheConfig struct {
+	Addr string `env:"ADDR" required:"true"`
+}
+
+type CommonConfig struct {
+	LogLevel string `env:"LOG_LEVEL" default:"info"`
+}
+
+func TestParseConfig_Nested(t *testing.T) {
+	t.Run("deeply nested keys are composed from prefixes", func(t *testing.T) {
+		prepareEnv(t,
+			"APP_STORAGE_POSTGRES_HOST", "localhost",
+			"APP_STORAGE_POSTGRES_POOL_MAX", "42",
+		)
+
+		cfg := &struct {
+			App appConfig `env:"APP"`
+		}{}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.NotNil(t, cfg.App.Storage.Postgres)
+		require.Equal(t, "localhost", cfg.App.Storage.Postgres.Host)
+		require.Equal(t, 42, cfg.App.Storage.Postgres.Pool.Max)
+	})
+
+	t.Run("nil pointer is allocated on demand", func(t *testing.T) {
+		prepareEnv(t,
+			"STORAGE_POSTGRES_HOST", "localhost",
+			"CACHE_ADDR", "localhost:6379",
+		)
+
+		cfg := &appConfig{}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.NotNil(t, cfg.Storage.Postgres)
+		require.Equal(t, 10, cfg.Storage.Postgres.Pool.Max) // Default value
+		require.NotNil(t, cfg.Cache)
+		require.Equal(t, "localhost:6379", cfg.Cache.Addr)
+	})
+
+	t.Run("nil pointer stays nil without env values", func(t *testing.T) {
+		cfg := &appConfig{}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.Nil(t, cfg.Storage.Postgres)
+		require.Nil(t, cfg.Cache)
+	})
+
+	t.Run("non-nil pointer is filled in place", func(t *testing.T) {
+		prepareEnv(t,
+			"CACHE_ADDR", "localhost:6379",
+		)
+
+		cache := &CacheConfig{}
+		cfg := &appConfig{Cache: cache}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.Equal(t, cache, cfg.Cache)
+		require.Equal(t, "localhost:6379", cache.Addr)
+	})
+
+	t.Run("required field of allocated pointer", func(t *testing.T) {
+		prepareEnv(t,
+			"STORAGE_POSTGRES_POOL_MAX", "42",
+		)
+
+		cfg := &appConfig{}
+		err := ParseConfig(cfg)
+		require.ErrorIs(t, err, ErrRequired)
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "STORAGE_POSTGRES_HOST", fieldErr.Key)
+		require.Equal(t, "Storage.Postgres.Host", fieldErr.Field)
+	})
+
+	t.Run("inline struct has no prefix", func(t *testing.T) {
+		prepareEnv(t,
+			"LOG_LEVEL", "debug",
+		)
+
+		cfg := &appConfig{}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.Equal(t, "debug", cfg.Common.LogLevel)
+	})
+
+	t.Run("unexported fields are skipped", func(t *testing.T) {
+		prepareEnv(t,
+			"HOST", "localhost",
+		)
+
+		cfg := &struct {
+			Host   string `env:"HOST"`
+			secret string `env:"SECRET"`
+		}{}
+		err := ParseConfig(cfg)
+		require.NoError(t, err)
+
+		require.Equal(t, "localhost", cfg.Host)
+		require.Equal(t, "", cfg.secret)
+	})
+}
+
+type rawValue struct {
+	Called bool
+	Value  string
+}
+
+func (r *rawValue) UnmarshalEnv(v string) error {
+	r.Called = true
+	r.Value = v
+	return nil
+}
+
+type emptyConfig struct {
+	Str            string   `env:"STR" default:"default"`
+	Slice          []string `env:"SLICE" default:"a,b"`
+	Custom         rawValue `env:"CUSTOM" default:"default"`
+	NotEmptyStr    string   `env:"NOT_EMPTY_STR" default:"default" notEmpty:"true"`
+	NotEmptySlice  []string `env:"NOT_EMPTY_SLICE" default:"a,b" notEmpty:"true"`
+	NotEmptyCustom rawValue `env:"NOT_EMPTY_CUSTOM" default:"default" notEmpty:"true"`
+}
+
+func TestParseConfig_Empty(t *testing.T) {
+	t.Run("empty value is not replaced by default", func(t *testing.T) {
+		cfg := &emptyConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"STR":    "",
+			"SLICE":  "",
+			"CUSTOM": "",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "", cfg.Str)
+		require.NotNil(t, cfg.Slice)
+		require.Equal(t, 0, len(cfg.Slice))
+		require.Equal(t, rawValue{Called: true, Value: ""}, cfg.Custom)
+	})
+
+	t.Run("empty value is replaced by default with notEmpty tag", func(t *testing.T) {
+		cfg := &emptyConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"NOT_EMPTY_STR":    "",
+			"NOT_EMPTY_SLICE":  "",
+			"NOT_EMPTY_CUSTOM": "",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "default", cfg.NotEmptyStr)
+		require.EqualValues(t, []string{"a", "b"}, cfg.NotEmptySlice)
+		require.Equal(t, rawValue{Called: true, Value: "default"}, cfg.NotEmptyCustom)
+	})
+
+	t.Run("unset value is replaced by default", func(t *testing.T) {
+		cfg := &emptyConfig{}
+		err := ParseConfigFrom(cfg, MapSource{})
+		require.NoError(t, err)
+
+		require.Equal(t, "default", cfg.Str)
+		require.EqualValues(t, []string{"a", "b"}, cfg.Slice)
+		require.Equal(t, rawValue{Called: true, Value: "default"}, cfg.Custom)
+	})
+
+	t.Run("unset value without default leaves field untouched", func(t *testing.T) {
+		cfg := &struct {
+			Str    string   `env:"STR"`
+			Int    int      `env:"INT"`
+			Slice  []string `env:"SLICE"`
+			Custom rawValue `env:"CUSTOM"`
+		}{Str: "initial", Int: 42}
+		err := ParseConfigFrom(cfg, MapSource{})
+		require.NoError(t, err)
+
+		require.Equal(t, "initial", cfg.Str)
+		require.Equal(t, 42, cfg.Int)
+		require.Nil(t, cfg.Slice)
+		require.False(t, cfg.Custom.Called)
+	})
+
+	t.Run("empty value satisfies required", func(t *testing.T) {
+		cfg := &struct {
+			Str    string   `env:"STR" required:"true"`
+			Slice  []string `env:"SLICE" required:"true"`
+			Custom rawValue `env:"CUSTOM" required:"true"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"STR":    "",
+			"SLICE":  "",
+			"CUSTOM": "",
+		})
+		require.NoError(t, err)
+		require.True(t, cfg.Custom.Called)
+	})
+
+	t.Run("empty value does not satisfy required with notEmpty tag", func(t *testing.T) {
+		cfg := &struct {
+			Str    string   `env:"STR" required:"true" notEmpty:"true"`
+			Slice  []string `env:"SLICE" required:"true" notEmpty:"true"`
+			Custom rawValue `env:"CUSTOM" required:"true" notEmpty:"true"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"STR":    "",
+			"SLICE":  "",
+			"CUSTOM": "",
+		})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 3, len(configErr.Errors))
+		for _, fieldErr := range configErr.Errors {
+			require.ErrorIs(t, fieldErr, ErrRequired)
+		}
+	})
+
+	t.Run("empty value of non-string type fails to parse", func(t *testing.T) {
+		cfg := &struct {
+			Int int `env:"INT"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"INT": ""})
+		require.Error(t, err)
+	})
+}
+
+func TestParseConfig_Collections(t *testing.T) {
+	t.Run("slices and maps", func(t *testing.T) {
+		cfg := &struct {
+			Hosts   []string          `env:"HOSTS"`
+			Ports   []int             `env:"PORTS" sep:";"`
+			Labels  map[string]string `env:"LABELS"`
+			Weights map[string]int    `env:"WEIGHTS" sep:";" kvsep:"="`
+			Flags   map[int]bool      `env:"FLAGS"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"HOSTS":   " a , b ,c ",
+			"PORTS":   "80; 443",
+			"LABELS":  "env:prod, team : core",
+			"WEIGHTS": "a=1;b=2",
+			"FLAGS":   "1:true,2:false",
+		})
+		require.NoError(t, err)
+
+		require.EqualValues(t, []string{"a", "b", "c"}, cfg.Hosts)
+		require.EqualValues(t, []int{80, 443}, cfg.Ports)
+		require.EqualValues(t, map[string]string{"env": "prod", "team": "core"}, cfg.Labels)
+		require.EqualValues(t, map[string]int{"a": 1, "b": 2}, cfg.Weights)
+		require.EqualValues(t, map[int]bool{1: true, 2: false}, cfg.Flags)
+	})
+
+	t.Run("escaped separators", func(t *testing.T) {
+		cfg := &struct {
+			Items  []string          `env:"ITEMS"`
+			Labels map[string]string `env:"LABELS"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"ITEMS":  `a\,b,c\\`,
+			"LABELS": `url:http\://host\,x,path:C:\dir`,
+		})
+		require.NoError(t, err)
+
+		require.EqualValues(t, []string{"a,b", `c\`}, cfg.Items)
+		require.EqualValues(t, map[string]string{"url": "http://host,x", "path": `C:\dir`}, cfg.Labels)
+	})
+
+	t.Run("empty map", func(t *testing.T) {
+		cfg := &struct {
+			Labels map[string]string `env:"LABELS"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"LABELS": ""})
+		require.NoError(t, err)
+
+		require.NotNil(t, cfg.Labels)
+		require.Equal(t, 0, len(cfg.Labels))
+	})
+
+	t.Run("invalid map entry", func(t *testing.T) {
+		cfg := &struct {
+			Labels map[string]int `env:"LABELS"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"LABELS": "a:1,b"})
+		require.Error(t, err)
+
+		err = ParseConfigFrom(cfg, MapSource{"LABELS": "a:x"})
+		require.Error(t, err)
+	})
+
+	t.Run("elements of custom types", func(t *testing.T) {
+		cfg := &struct {
+			Durations []CustomDuration            `env:"DURATIONS"`
+			Values    []*rawValue                 `env:"VALUES" sep:"|"`
+			ByName    map[string]CustomDuration   `env:"BY_NAME"`
+			Nested    map[string][]CustomDuration `env:"NESTED" sep:";" kvsep:"="`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"DURATIONS": "1d, 2h",
+			"VALUES":    "a|b",
+			"BY_NAME":   "short:1h,long:2d",
+			"NESTED":    "a=1h",
+		})
+		require.NoError(t, err)
+
+		require.EqualValues(t, []CustomDuration{CustomDuration(24 * time.Hour), CustomDuration(2 * time.Hour)}, cfg.Durations)
+		require.Equal(t, 2, len(cfg.Values))
+		require.Equal(t, "a", cfg.Values[0].Value)
+		require.Equal(t, "b", cfg.Values[1].Value)
+		require.EqualValues(t, map[string]CustomDuration{
+			"short": CustomDuration(time.Hour),
+			"long":  CustomDuration(48 * time.Hour),
+		}, cfg.ByName)
+		require.EqualValues(t, map[string][]CustomDuration{"a": {CustomDuration(time.Hour)}}, cfg.Nested)
+	})
+}
+
+type color int
+
+func (c *color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = 1
+	case "green":
+		*c = 2
+	default:
+		return errs.Newf("unknown color %q", text)
+	}
+	return nil
+}
+
+type point struct {
+	X, Y int
+}
+
+func (p *point) UnmarshalText(text []byte) error {
+	x, y, ok := strings.Cut(string(text), ";")
+	if !ok {
+		return errs.New("expected x;y")
+	}
+	var err error
+	if p.X, err = strconv.Atoi(x); err != nil {
+		return err
+	}
+	p.Y, err = strconv.Atoi(y)
+	return err
+}
+
+type upperFlag string
+
+func (f *upperFlag) String() string { return string(*f) }
+
+func (f *upperFlag) Set(v string) error {
+	*f = upperFlag(strings.ToUpper(v))
+	return nil
+}
+
+type tagSet map[string]bool
+
+func (s tagSet) String() string { return fmt.Sprint(map[string]bool(s)) }
+
+func (s tagSet) Set(v string) error {
+	s[v] = true
+	return nil
+}
+
+// prioritized implements all unmarshalers to check their precedence.
+type prioritized struct {
+	Method string
+}
+
+func (p *prioritized) UnmarshalEnv(string) error {
+	p.Method = "env"
+	return nil
+}
+
+func (p *prioritized) UnmarshalText([]byte) error {
+	p.Method = "text"
+	return nil
+}
+
+func (p *prioritized) Set(string) error {
+	p.Method = "set"
+	return nil
+}
+
+type textAndSet struct {
+	Method string
+}
+
+func (p *textAndSet) UnmarshalText([]byte) error {
+	p.Method = "text"
+	return nil
+}
+
+func (p *textAndSet) Set(string) error {
+	p.Method = "set"
+	return nil
+}
+
+func TestParseConfig_Unmarshalers(t *testing.T) {
+	t.Run("text unmarshaler", func(t *testing.T) {
+		cfg := &struct {
+			Level    slog.Level  `env:"LEVEL"`
+			LevelPtr *slog.Level `env:"LEVEL_PTR"`
+			Color    color       `env:"COLOR"`
+			Colors   []color     `env:"COLORS"`
+			Point    point       `env:"POINT"`
+			PointPtr *point      `env:"POINT_PTR"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"LEVEL":     "warn",
+			"LEVEL_PTR": "debug",
+			"COLOR":     "red",
+			"COLORS":    "red,green",
+			"POINT":     "1;2",
+			"POINT_PTR": "3;4",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, slog.LevelWarn, cfg.Level)
+		require.Equal(t, slog.LevelDebug, *cfg.LevelPtr)
+		require.Equal(t, color(1), cfg.Color)
+		require.EqualValues(t, []color{1, 2}, cfg.Colors)
+		require.Equal(t, point{X: 1, Y: 2}, cfg.Point)
+		require.Equal(t, point{X: 3, Y: 4}, *cfg.PointPtr)
+	})
+
+	t.Run("text unmarshaler error", func(t *testing.T) {
+		cfg := &struct {
+			Color color `env:"COLOR"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"COLOR": "blue"})
+		require.Error(t, err)
+	})
+
+	t.Run("flag value", func(t *testing.T) {
+		cfg := &struct {
+			Name    upperFlag  `env:"NAME"`
+			NamePtr *upperFlag `env:"NAME_PTR"`
+			Tags    tagSet     `env:"TAGS"`
+		}{Tags: tagSet{}}
+		err := ParseConfigFrom(cfg, MapSource{
+			"NAME":     "foo",
+			"NAME_PTR": "bar",
+			"TAGS":     "baz",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, upperFlag("FOO"), cfg.Name)
+		require.Equal(t, upperFlag("BAR"), *cfg.NamePtr)
+		require.EqualValues(t, tagSet{"baz": true}, cfg.Tags)
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		cfg := &struct {
+			All prioritized `env:"ALL"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"ALL": "value"})
+		require.NoError(t, err)
+		require.Equal(t, "env", cfg.All.Method)
+
+		textCfg := &struct {
+			TextAndSet textAndSet `env:"TEXT_AND_SET"`
+		}{}
+		err = ParseConfigFrom(textCfg, MapSource{"TEXT_AND_SET": "value"})
+		require.NoError(t, err)
+		require.Equal(t, "text", textCfg.TextAndSet.Method)
+	})
+}
+
 func prepareEnv(t *testing.T, envValue ...string) {
 	t.Helper()
 	if len(envValue)%2 != 0 {
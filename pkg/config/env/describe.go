@@ -0,0 +1,170 @@
+package env
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// Var describes a single key of the config.
+type Var struct {
+	Key        string // env key, e.g. DB_HOST
+	Field      string // Go path of the field, e.g. Db.Host
+	Type       string // Go type of the field, e.g. time.Duration
+	Default    string // value of `default` tag
+	HasDefault bool   // reports whether field has `default` tag, which can be empty
+	Required   bool   // reports whether field has `required:"true"` tag
+	Secret     bool   // reports whether field is a secret, its default is redacted
+	Desc       string // value of `desc` tag
+}
+
+// Vars is a list of config keys in order of declaration of the fields.
+type Vars []Var
+
+// Describe lists keys of the config using the same tags as ParseConfig.
+// Only naming options like KeyPrefix are used from opts.
+// cfg must be a struct or a pointer to a struct, pointer can be nil:
+//
+//	vars, err := env.Describe((*Config)(nil))
+//	fmt.Println(vars.Markdown())
+func Describe(cfg any, opts ...Option) (Vars, error) {
+	t := reflect.TypeOf(cfg)
+	if t == nil {
+		return nil, errs.New("config must be a struct or a pointer to a struct")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errs.New("config must be a struct or a pointer to a struct")
+	}
+
+	return describeStruct(newOptions(opts).naming, t, "", "", nil), nil
+}
+
+// describeStruct lists keys of struct type t, elements of collections get placeholder keys like UPSTREAMS_<N>_HOST.
+// Collections of already described types are skipped, visited holds them.
+func describeStruct(n naming, t reflect.Type, prefix, path string, visited []reflect.Type) Vars {
+	if slices.Contains(visited, t) {
+		return nil
+	}
+	visited = append(visited, t)
+
+	var vars Vars
+	n.walkFields(t, prefix, path, nil, func(field reflect.StructField, key, path string) bool {
+		switch {
+		case isStructSlice(field.Type):
+			elemKey := n.join(key, "<N>")
+			vars = append(vars, describeStruct(n.element(elemKey), structType(field.Type.Elem()), elemKey, path+"[N]", visited)...)
+			return true
+		case isStructMap(field.Type):
+			elemKey := n.join(key, "<NAME>")
+			vars = append(vars, describeStruct(n.element(elemKey), structType(field.Type.Elem()), elemKey, path+"[NAME]", visited)...)
+			return true
+		}
+
+		defaultVal, hasDefault := field.Tag.Lookup("default")
+		secret := isSecret(field)
+		if secret && defaultVal != "" {
+			defaultVal = redacted
+		}
+		vars = append(vars, Var{
+			Key:        key,
+			Field:      path,
+			Type:       field.Type.String(),
+			Default:    defaultVal,
+			HasDefault: hasDefault,
+			Required:   field.Tag.Get("required") == "true",
+			Secret:     secret,
+			Desc:       field.Tag.Get("desc"),
+		})
+		return true
+	})
+	return vars
+}
+
+// Markdown renders keys as a markdown table.
+func (vars Vars) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString("| Key | Type | Default | Required | Description |\n")
+	sb.WriteString("|-----|------|---------|----------|-------------|\n")
+	for _, v := range vars {
+		defaultVal := ""
+		if v.HasDefault {
+			defaultVal = "`" + v.Default + "`"
+		}
+		required := "no"
+		if v.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&sb, "| `%s` | `%s` | %s | %s | %s |\n",
+			v.Key, markdownCell(v.Type), markdownCell(defaultVal), required, markdownCell(v.Desc))
+	}
+	return sb.String()
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// DotEnv renders keys as a commented .env.example file.
+// Keys with default are set to the default, secrets and keys without default are left empty:
+//
+//	# Database host
+//	# string, required
+//	DB_HOST=
+func (vars Vars) DotEnv() string {
+	var sb strings.Builder
+	for i, v := range vars {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if v.Desc != "" {
+			fmt.Fprintf(&sb, "# %s\n", v.Desc)
+		}
+		if v.Required {
+			fmt.Fprintf(&sb, "# %s, required\n", v.Type)
+		} else {
+			fmt.Fprintf(&sb, "# %s\n", v.Type)
+		}
+		value := v.Default
+		if v.Secret {
+			value = ""
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", v.Key, quoteDotEnv(value))
+	}
+	return sb.String()
+}
+
+// Help renders keys in the format of flag.PrintDefaults, suitable for --help output:
+//
+//	Environment variables:
+//	  DB_HOST string
+//	    	Database host (required)
+//	  DB_PORT int
+//	    	(default "5432")
+func (vars Vars) Help() string {
+	var sb strings.Builder
+	sb.WriteString("Environment variables:\n")
+	for _, v := range vars {
+		fmt.Fprintf(&sb, "  %s %s\n", v.Key, v.Type)
+
+		var details []string
+		if v.Desc != "" {
+			details = append(details, v.Desc)
+		}
+		if v.Required {
+			details = append(details, "(required)")
+		}
+		if v.HasDefault {
+			details = append(details, fmt.Sprintf("(default %q)", v.Default))
+		}
+		if len(details) > 0 {
+			fmt.Fprintf(&sb, "    \t%s\n", strings.Join(details, " "))
+		}
+	}
+	return sb.String()
+}
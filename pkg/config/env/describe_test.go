@@ -0,0 +1,121 @@
+package env
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type describedConfig struct {
+	Db struct {
+		Host string `env:"HOST" required:"true" desc:"Database host"`
+		Port int    `env:"PORT" default:"5432"`
+	} `env:"DB"`
+	Cache   *CacheConfig  `env:"CACHE"`
+	Timeout time.Duration `env:"TIMEOUT" default:"10s" desc:"Request timeout"`
+	Greet   string        `env:"GREET" default:"hello world"`
+}
+
+func TestDescribe(t *testing.T) {
+	t.Run("keys are listed in order of fields", func(t *testing.T) {
+		vars, err := Describe((*describedConfig)(nil))
+		require.NoError(t, err)
+
+		require.EqualValues(t, Vars{
+			{Key: "DB_HOST", Field: "Db.Host", Type: "string", Required: true, Desc: "Database host"},
+			{Key: "DB_PORT", Field: "Db.Port", Type: "int", Default: "5432", HasDefault: true},
+			{Key: "CACHE_ADDR", Field: "Cache.Addr", Type: "string", Required: true},
+			{Key: "TIMEOUT", Field: "Timeout", Type: "time.Duration", Default: "10s", HasDefault: true, Desc: "Request timeout"},
+			{Key: "GREET", Field: "Greet", Type: "string", Default: "hello world", HasDefault: true},
+		}, vars)
+	})
+
+	t.Run("struct value", func(t *testing.T) {
+		vars, err := Describe(describedConfig{})
+		require.NoError(t, err)
+		require.Equal(t, 5, len(vars))
+	})
+
+	t.Run("collections of structs", func(t *testing.T) {
+		vars, err := Describe((*collectionsConfig)(nil))
+		require.NoError(t, err)
+
+		keys := make([]string, 0, len(vars))
+		for _, v := range vars {
+			keys = append(keys, v.Key)
+		}
+		require.EqualValues(t, []string{
+			"UPSTREAMS_<N>_HOST", "UPSTREAMS_<N>_PORT", "UPSTREAMS_<N>_WEIGHT",
+			"BACKUPS_<N>_HOST", "BACKUPS_<N>_PORT", "BACKUPS_<N>_WEIGHT",
+			"TENANTS_<NAME>_HOST", "TENANTS_<NAME>_DB_HOST", "TENANTS_<NAME>_PASSWORD",
+			"SHARDS_<NAME>_HOST", "SHARDS_<NAME>_PORT", "SHARDS_<NAME>_WEIGHT",
+		}, keys)
+		require.Equal(t, "Upstreams[N].Host", vars[0].Field)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := Describe(nil)
+		require.Error(t, err)
+
+		_, err = Describe(42)
+		require.Error(t, err)
+	})
+}
+
+func TestVars_Render(t *testing.T) {
+	vars, err := Describe((*describedConfig)(nil))
+	require.NoError(t, err)
+	vars = vars[:2]
+
+	t.Run("markdown", func(t *testing.T) {
+		expected := "| Key | Type | Default | Required | Description |\n" +
+			"|-----|------|---------|----------|-------------|\n" +
+			"| `DB_HOST` | `string` |  | yes | Database host |\n" +
+			"| `DB_PORT` | `int` | `5432` | no |  |\n"
+		require.Equal(t, expected, vars.Markdown())
+	})
+
+	t.Run("dotenv", func(t *testing.T) {
+		expected := `# Database host
+# string, required
+DB_HOST=
+
+# int
+DB_PORT=5432
+`
+		require.Equal(t, expected, vars.DotEnv())
+	})
+
+	t.Run("dotenv quotes defaults", func(t *testing.T) {
+		greet := Vars{{Key: "GREET", Type: "string", Default: "hello world", HasDefault: true}}
+		require.Equal(t, "# string\nGREET=\"hello world\"\n", greet.DotEnv())
+	})
+
+	t.Run("dotenv round trip", func(t *testing.T) {
+		example := Vars{
+			{Key: "GREETING", Type: "string", Default: "it's me", HasDefault: true, Desc: "Greeting"},
+			{Key: "BANNER", Type: "string", Default: "line 1\nline \"2\" # not a comment", HasDefault: true},
+			{Key: "PASS", Type: "string", Default: redacted, HasDefault: true, Secret: true},
+			{Key: "HOST", Type: "string", Required: true},
+		}
+		parsed, err := ParseDotEnv([]byte(example.DotEnv()))
+		require.NoError(t, err)
+		require.EqualValues(t, map[string]string{
+			"GREETING": "it's me",
+			"BANNER":   "line 1\nline \"2\" # not a comment",
+			"PASS":     "",
+			"HOST":     "",
+		}, parsed.Map())
+	})
+
+	t.Run("help", func(t *testing.T) {
+		expected := `Environment variables:
+  DB_HOST string
+    	Database host (required)
+  DB_PORT int
+    	(default "5432")
+`
+		require.Equal(t, expected, vars.Help())
+	})
+}
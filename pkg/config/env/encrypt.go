@@ -0,0 +1,247 @@
+package env
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/cryptokit"
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// Values and whole .env files can be encrypted with cryptokit.AesCryptor and committed to the repository:
+//
+//	DB_PASSWORD=enc:<base64>
+//
+// Encrypted values are decrypted by ParseConfig, LoadDotEnv and DotEnvSource
+// with base64 key from DOTENV_KEY variable or from file at DOTENV_KEY_FILE.
+// Decrypted values are expanded as unquoted values of .env file, if the source and the field allow it.
+// Whole file is encrypted as a single enc:<base64> value, see EncryptDotEnvFile.
+
+const (
+	encryptedPrefix = "enc:"
+	keyVar          = "DOTENV_KEY"
+)
+
+// GenerateDotEnvKey returns a random key for AES-256 encoded with base64, ready for DOTENV_KEY.
+func GenerateDotEnvKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", errs.Wrap(err, "failed to generate key")
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptDotEnv encrypts values of .env file at path in place, comments and formatting are kept.
+// Values that are already encrypted are left untouched. key is base64 encoded, see GenerateDotEnvKey.
+func EncryptDotEnv(path, key string) error {
+	cryptor, err := newCryptor(key)
+	if err != nil {
+		return err
+	}
+	return rewriteDotEnvValues(path, func(v DotEnvVar) (string, error) {
+		if isEncrypted(v) {
+			return "", nil
+		}
+		return encryptValue(cryptor, v.sourceValue())
+	})
+}
+
+// EncryptDotEnvFile encrypts the whole .env file at path in place, so even names of the variables are hidden.
+func EncryptDotEnvFile(path, key string) error {
+	cryptor, err := newCryptor(key)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Wrapf(err, "failed to read %s", path)
+	}
+	if isEncryptedFile(file) {
+		return errs.Newf("%s is already encrypted", path)
+	}
+
+	encrypted, err := encryptValue(cryptor, string(file))
+	if err != nil {
+		return err
+	}
+	return writeFileKeepMode(path, []byte(encrypted+"\n"))
+}
+
+// RotateDotEnvKey re-encrypts encrypted values or the whole encrypted .env file at path with newKey.
+func RotateDotEnvKey(path, oldKey, newKey string) error {
+	oldCryptor, err := newCryptor(oldKey)
+	if err != nil {
+		return errs.Wrap(err, "invalid old key")
+	}
+	newCryptor, err := newCryptor(newKey)
+	if err != nil {
+		return errs.Wrap(err, "invalid new key")
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Wrapf(err, "failed to read %s", path)
+	}
+	if isEncryptedFile(file) {
+		plain, err := decryptValue(oldCryptor, strings.TrimSpace(string(file)))
+		if err != nil {
+			return err
+		}
+		encrypted, err := encryptValue(newCryptor, plain)
+		if err != nil {
+			return err
+		}
+		return writeFileKeepMode(path, []byte(encrypted+"\n"))
+	}
+
+	return rewriteDotEnvValues(path, func(v DotEnvVar) (string, error) {
+		if !isEncrypted(v) {
+			return "", nil
+		}
+		plain, err := decryptValue(oldCryptor, v.Value)
+		if err != nil {
+			return "", errs.Wrapf(err, "failed to decrypt %s", v.Name)
+		}
+		return encryptValue(newCryptor, plain)
+	})
+}
+
+// rewriteDotEnvValues replaces raw values of the file with values returned by replace.
+// Values for which replace returns empty string are kept.
+func rewriteDotEnvValues(path string, replace func(DotEnvVar) (string, error)) error {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return errs.Wrapf(err, "failed to read %s", path)
+	}
+	vars, spans, err := scanDotEnv(file)
+	if err != nil {
+		return errs.Wrapf(err, "failed to parse %s", path)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for i, v := range vars {
+		value, err := replace(v)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			continue
+		}
+		sb.Write(file[last:spans[i].start])
+		sb.WriteString(value)
+		last = spans[i].end
+	}
+	sb.Write(file[last:])
+
+	return writeFileKeepMode(path, []byte(sb.String()))
+}
+
+func writeFileKeepMode(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errs.Wrapf(err, "failed to stat %s", path)
+	}
+	if err := os.WriteFile(path, data, info.Mode().Perm()); err != nil {
+		return errs.Wrapf(err, "failed to write %s", path)
+	}
+	return nil
+}
+
+func isEncrypted(v DotEnvVar) bool {
+	return !v.Literal && strings.HasPrefix(v.Value, encryptedPrefix)
+}
+
+// isEncryptedFile reports whether the whole file is encrypted.
+// Names of variables can't contain ':', so enc: can't start a line of plain .env file.
+func isEncryptedFile(file []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(file)), encryptedPrefix)
+}
+
+func encryptValue(cryptor *cryptokit.AesCryptor, value string) (string, error) {
+	encrypted, err := cryptor.Encrypt([]byte(value))
+	if err != nil {
+		return "", errs.Wrap(err, "failed to encrypt value")
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+func decryptValue(cryptor *cryptokit.AesCryptor, value string) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", errs.Wrap(err, "failed to decode encrypted value")
+	}
+	decrypted, err := cryptor.Decrypt(encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+func newCryptor(key string) (*cryptokit.AesCryptor, error) {
+	rawKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to decode key, base64 is expected")
+	}
+	return cryptokit.NewEncrypter(rawKey)
+}
+
+// decrypter decrypts values with the key loaded on first use,
+// so configs without encrypted values don't need the key.
+type decrypter struct {
+	lookup  func(key string) (string, bool)
+	cryptor *cryptokit.AesCryptor
+}
+
+func (d *decrypter) decrypt(value string) (string, error) {
+	if d.cryptor == nil {
+		key, err := d.loadKey()
+		if err != nil {
+			return "", err
+		}
+		if d.cryptor, err = newCryptor(key); err != nil {
+			return "", errs.Wrapf(err, "invalid %s", keyVar)
+		}
+	}
+	return decryptValue(d.cryptor, value)
+}
+
+func (d *decrypter) loadKey() (string, error) {
+	if key, ok := d.lookup(keyVar); ok {
+		return key, nil
+	}
+	if path, ok := d.lookup(keyVar + fileSuffix); ok {
+		return readValueFile(path)
+	}
+	return "", errs.Newf("encrypted value requires %s or %s%s", keyVar, keyVar, fileSuffix)
+}
+
+// decryptDotEnv parses .env file, decrypting the whole file or its values.
+func decryptDotEnv(file []byte, d *decrypter) (DotEnvVars, error) {
+	if isEncryptedFile(file) {
+		plain, err := d.decrypt(strings.TrimSpace(string(file)))
+		if err != nil {
+			return nil, errs.Wrap(err, "failed to decrypt file")
+		}
+		file = []byte(plain)
+	}
+
+	vars, err := parseDotEnv(file)
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vars {
+		if !isEncrypted(v) {
+			continue
+		}
+		plain, err := d.decrypt(v.Value)
+		if err != nil {
+			return nil, errs.Wrapf(err, "line %d: failed to decrypt %s", v.Line, v.Name)
+		}
+		vars[i].Value = plain
+	}
+	return vars, nil
+}
@@ -2,38 +2,212 @@ package env
 
 import (
 	"bytes"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pechorka/gostdlib/pkg/errs"
-	"github.com/pechorka/gostdlib/pkg/stringx"
 )
 
+// ExportDotEnv sets variables of ./.env file to process environment, overriding existing ones.
+// It's LoadDotEnv without options.
 func ExportDotEnv() error {
-	file, err := os.ReadFile(".env")
-	if err != nil {
-		return errs.Wrap(err, "failed to read .env file")
+	return LoadDotEnv()
+}
+
+// LoadDotEnv sets variables of .env files to process environment:
+//
+//	err := env.LoadDotEnv(
+//		env.Files(".env"),
+//		env.OptionalFiles(".env.local", ".env.${APP_ENV}"),
+//		env.NoOverride(),
+//		env.SearchParents(),
+//	)
+//
+// Without options ./.env is required and its variables override process environment.
+// Later files override earlier ones. References to other variables are expanded across all files,
+// see expander for the syntax. References in file paths are expanded with process environment,
+// braces are optional there: .env.$APP_ENV is the same as .env.${APP_ENV}.
+func LoadDotEnv(opts ...LoadOption) error {
+	o := loadOptions{override: true}
+	for _, opt := range opts {
+		opt(&o)
 	}
-	if err := exportDotEnv(file); err != nil {
-		return errs.Wrap(err, "failed to export .env file")
+	if len(o.files) == 0 {
+		o.files = []dotEnvFile{{path: ".env"}}
+	}
+
+	d := &decrypter{lookup: os.LookupEnv}
+	var entries []DotEnvVar
+	for _, f := range o.files {
+		fileEntries, err := f.read(o.searchParents, d)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, fileEntries...)
 	}
 
+	if err := exportEntries(entries, o.override); err != nil {
+		return errs.Wrap(err, "failed to export .env files")
+	}
 	return nil
 }
 
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	files         []dotEnvFile
+	override      bool
+	searchParents bool
+}
+
+// Files adds .env files which must exist.
+func Files(paths ...string) LoadOption {
+	return func(o *loadOptions) {
+		for _, path := range paths {
+			o.files = append(o.files, dotEnvFile{path: path})
+		}
+	}
+}
+
+// OptionalFiles adds .env files which are skipped if they don't exist.
+func OptionalFiles(paths ...string) LoadOption {
+	return func(o *loadOptions) {
+		for _, path := range paths {
+			o.files = append(o.files, dotEnvFile{path: path, optional: true})
+		}
+	}
+}
+
+// NoOverride keeps variables which are already set in process environment.
+func NoOverride() LoadOption {
+	return func(o *loadOptions) {
+		o.override = false
+	}
+}
+
+// SearchParents looks for relative files in parent directories if they aren't found in the working directory,
+// so tests running in subpackages find .env in the repository root. The nearest file wins.
+func SearchParents() LoadOption {
+	return func(o *loadOptions) {
+		o.searchParents = true
+	}
+}
+
+type dotEnvFile struct {
+	path     string
+	optional bool
+}
+
+// read parses and decrypts the file, nil entries are returned for missing optional file.
+func (f dotEnvFile) read(searchParents bool, d *decrypter) ([]DotEnvVar, error) {
+	path, err := (&expander{lookup: lookupProcessEnv, bare: true}).expandValue(f.path, nil)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to expand path %s", f.path)
+	}
+	if searchParents {
+		path = findInParents(path)
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		if f.optional && errs.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errs.Wrapf(err, "failed to read %s", path)
+	}
+
+	entries, err := decryptDotEnv(file, d)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to parse %s", path)
+	}
+	return entries, nil
+}
+
+// findInParents returns path of the nearest file with relative path in working directory or its parents.
+// path is returned as is if it's absolute or the file isn't found.
+func findInParents(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	for {
+		candidate := filepath.Join(dir, path)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return path
+		}
+		dir = parent
+	}
+}
+
+// exportDotEnv sets variables of the file to process environment, overriding existing ones.
 func exportDotEnv(file []byte) error {
-	lines := bytes.Split(file, []byte("\n"))
-	for _, line := range lines {
-		line = bytes.TrimSpace(line)
-		if len(line) == 0 || bytes.HasPrefix(line, []byte("#")) {
+	entries, err := decryptDotEnv(file, &decrypter{lookup: os.LookupEnv})
+	if err != nil {
+		return err
+	}
+	return exportEntries(entries, true)
+}
+
+// exportEntries sets variables to process environment, later entries override earlier ones.
+// References to other variables are expanded, see expander for the syntax.
+// Values in single quotes are not expanded.
+// If override is false, variables already set in process environment are kept
+// and references to them are expanded to their process values.
+// Reference to the variable itself is expanded to its earlier definition or process value: PATH=${PATH}:/x.
+func exportEntries(entries []DotEnvVar, override bool) error {
+	definitions := make(map[string][]string, len(entries))
+	fileVars := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if _, ok := os.LookupEnv(entry.Name); ok && !override {
 			continue
 		}
-		name, value, ok := bytes.Cut(line, []byte("="))
-		if !ok {
-			return errs.Newf("invalid line: %s", line)
+		definitions[entry.Name] = append(definitions[entry.Name], entry.sourceValue())
+		fileVars[entry.Name] = entry.sourceValue()
+	}
+	e := expander{
+		lookup: func(key string) (string, bool) {
+			if value, ok := fileVars[key]; ok {
+				return value, true
+			}
+			return lookupProcessEnv(key)
+		},
+		previous: func(key string, n int) (string, bool) {
+			if i := len(definitions[key]) - 1 - n; i >= 0 {
+				return definitions[key][i], true
+			}
+			return lookupProcessEnv(key)
+		},
+	}
+
+	// expand all values before setting any of them, so references see values of the files
+	expanded := make(map[string]string, len(fileVars))
+	for _, entry := range entries {
+		value, ok := fileVars[entry.Name]
+		if _, done := expanded[entry.Name]; !ok || done {
+			continue
 		}
-		value = removeCommentsAndSpaces(value)
-		err := os.Setenv(stringx.FromBytes(name), stringx.FromBytes(value))
+		value, err := e.expand(entry.Name, value)
 		if err != nil {
+			return err
+		}
+		expanded[entry.Name] = value
+	}
+
+	for _, entry := range entries {
+		value, ok := expanded[entry.Name]
+		if !ok {
+			continue
+		}
+		if err := os.Setenv(entry.Name, value); err != nil {
 			return errs.Wrap(err, "failed to set environment variable")
 		}
 	}
@@ -41,22 +215,309 @@ func exportDotEnv(file []byte) error {
 	return nil
 }
 
-func removeCommentsAndSpaces(value []byte) []byte {
-	value = bytes.TrimSpace(value)
-	if len(value) == 0 {
+// lookupProcessEnv returns value of process environment escaped for expander, process values are used as is.
+func lookupProcessEnv(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	return strings.ReplaceAll(value, "$", "$$"), ok
+}
+
+// DotEnvVar is a variable defined in .env file.
+type DotEnvVar struct {
+	Name    string
+	Value   string // value with quotes removed and escapes decoded, references are not expanded
+	Literal bool   // value is in single quotes and must not be expanded
+	Line    int    // line where the variable is defined
+}
+
+// DotEnvVars are variables of .env file in order of appearance.
+type DotEnvVars []DotEnvVar
+
+// ParseDotEnv parses .env file without touching process environment, see parseDotEnv for the grammar.
+func ParseDotEnv(data []byte) (DotEnvVars, error) {
+	return parseDotEnv(data)
+}
+
+// ReadDotEnv reads .env file from r without touching process environment, see DotEnvVars.Map.
+// MapSource(values) expands references in all values including single-quoted ones,
+// use DotEnvSource to parse config from the file with single-quoted values kept literal.
+func ReadDotEnv(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read .env")
+	}
+	vars, err := parseDotEnv(data)
+	if err != nil {
+		return nil, err
+	}
+	return vars.Map(), nil
+}
+
+// Map returns decoded values by names, later definitions override earlier ones. References are not expanded.
+func (vars DotEnvVars) Map() map[string]string {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+// sourceValue returns value in the syntax of expander, $ in single-quoted values is escaped as $$ to keep them literal.
+func (v DotEnvVar) sourceValue() string {
+	if v.Literal {
+		return strings.ReplaceAll(v.Value, "$", "$$")
+	}
+	return v.Value
+}
+
+// parseDotEnv returns variables of the file in order of appearance.
+// The grammar follows docker compose and godotenv:
+//
+//	# comment
+//	KEY=value                 # unquoted value is trimmed, comment starts with whitespace and #
+//	export KEY=value          # export prefix is ignored
+//	KEY='literal ${NOT_EXPANDED}'
+//	KEY="escapes \n \t \" \\ are decoded, ${VAR} is expanded"
+//	KEY="multi
+//	line"                     # quoted values can span lines
+//
+// Syntax errors report line numbers.
+func parseDotEnv(file []byte) (DotEnvVars, error) {
+	entries, _, err := scanDotEnv(file)
+	return entries, err
+}
+
+// scanDotEnv parses the file and returns positions of raw values in it, including quotes.
+func scanDotEnv(file []byte) (DotEnvVars, []valueSpan, error) {
+	s := &dotEnvScanner{src: file, line: 1}
+	var entries []DotEnvVar
+	for {
+		s.skipBlankLines()
+		if s.eof() {
+			return entries, s.spans, nil
+		}
+		line := s.line
+		entry, err := s.entry()
+		if err != nil {
+			if s.errLine != 0 {
+				line = s.errLine
+			}
+			return nil, nil, errs.Newf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// valueSpan is position of raw value in .env file: file[start:end].
+type valueSpan struct {
+	start, end int
+}
+
+// dotEnvScanner reads entries of .env file one by one.
+type dotEnvScanner struct {
+	src   []byte
+	pos   int
+	line  int
+	spans []valueSpan
+
+	errLine int // line where error is raised if it isn't the line of the entry
+}
+
+func (s *dotEnvScanner) eof() bool {
+	return s.pos >= len(s.src)
+}
+
+func (s *dotEnvScanner) peek() byte {
+	if s.eof() {
+		return 0
+	}
+	return s.src[s.pos]
+}
+
+// next consumes current character, counting lines.
+func (s *dotEnvScanner) next() byte {
+	c := s.src[s.pos]
+	s.pos++
+	if c == '\n' {
+		s.line++
+	}
+	return c
+}
+
+// unterminated returns error of quoted value started at line start, which isn't closed till the end of file.
+// Error is raised at the last line, start line is reported only if it differs.
+func (s *dotEnvScanner) unterminated(start int) error {
+	s.errLine = s.line
+	if s.src[s.pos-1] == '\n' {
+		s.errLine--
+	}
+	if s.errLine != start {
+		return errs.Newf("unterminated quoted value started at line %d", start)
+	}
+	return errs.New("unterminated quoted value")
+}
+
+// skipSpaces skips spaces within the line.
+func (s *dotEnvScanner) skipSpaces() {
+	for !s.eof() && (s.peek() == ' ' || s.peek() == '\t' || s.peek() == '\r') {
+		s.pos++
+	}
+}
+
+// skipComment skips the rest of the line after #.
+func (s *dotEnvScanner) skipComment() {
+	for !s.eof() && s.peek() != '\n' {
+		s.pos++
+	}
+}
+
+// skipBlankLines skips empty lines and comment lines.
+func (s *dotEnvScanner) skipBlankLines() {
+	for !s.eof() {
+		s.skipSpaces()
+		switch s.peek() {
+		case '\n':
+			s.next()
+		case '#':
+			s.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// endLine expects end of the line, optionally with a comment.
+func (s *dotEnvScanner) endLine() error {
+	s.skipSpaces()
+	if s.peek() == '#' {
+		s.skipComment()
+	}
+	if s.eof() {
 		return nil
 	}
+	if s.peek() != '\n' {
+		return errs.Newf("unexpected %q after value", s.peek())
+	}
+	s.next()
+	return nil
+}
+
+func (s *dotEnvScanner) entry() (DotEnvVar, error) {
+	entry := DotEnvVar{Line: s.line}
+
+	entry.Name = s.name()
+	if entry.Name == "export" && (s.peek() == ' ' || s.peek() == '\t') {
+		s.skipSpaces()
+		entry.Name = s.name()
+	}
+	if entry.Name == "" {
+		return entry, errs.Newf("invalid variable name at %q", s.restOfLine())
+	}
+
+	s.skipSpaces()
+	if s.peek() != '=' {
+		return entry, errs.Newf("expected = after %s", entry.Name)
+	}
+	s.next()
+	s.skipSpaces()
+
+	start := s.pos
+	var err error
+	switch s.peek() {
+	case '\'':
+		entry.Value, err = s.singleQuoted()
+		entry.Literal = true
+	case '"':
+		entry.Value, err = s.doubleQuoted()
+	default:
+		entry.Value = s.unquoted()
+	}
+	if err != nil {
+		return entry, err
+	}
+	raw := bytes.TrimRight(s.src[start:s.pos], " \t\r")
+	s.spans = append(s.spans, valueSpan{start: start, end: start + len(raw)})
+	return entry, s.endLine()
+}
+
+// name reads variable name: letters, digits, _, . and -.
+func (s *dotEnvScanner) name() string {
+	start := s.pos
+	for !s.eof() {
+		c := s.peek()
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-' {
+			s.pos++
+			continue
+		}
+		break
+	}
+	return string(s.src[start:s.pos])
+}
+
+func (s *dotEnvScanner) restOfLine() string {
+	end := bytes.IndexByte(s.src[s.pos:], '\n')
+	if end == -1 {
+		return string(s.src[s.pos:])
+	}
+	return string(bytes.TrimRight(s.src[s.pos:s.pos+end], "\r"))
+}
 
-	if len(value) > 1 && value[0] == '\'' || value[0] == '"' {
-		quoteEnd := bytes.LastIndexByte(value, value[0])
-		if quoteEnd != -1 {
-			return value[1:quoteEnd]
+// unquoted reads value till the end of the line or comment, which starts with whitespace and #.
+func (s *dotEnvScanner) unquoted() string {
+	start := s.pos
+	for !s.eof() && s.peek() != '\n' {
+		if s.peek() == '#' && s.pos > start && (s.src[s.pos-1] == ' ' || s.src[s.pos-1] == '\t') {
+			break
 		}
+		s.pos++
 	}
+	return string(bytes.TrimSpace(s.src[start:s.pos]))
+}
 
-	i := bytes.IndexByte(value, '#')
-	if i != -1 {
-		return bytes.TrimRight(value[:i], " ")
+// singleQuoted reads value till the closing quote as is.
+func (s *dotEnvScanner) singleQuoted() (string, error) {
+	line := s.line
+	s.next()
+	start := s.pos
+	for !s.eof() {
+		if s.peek() == '\'' {
+			value := string(s.src[start:s.pos])
+			s.next()
+			return value, nil
+		}
+		s.next()
+	}
+	return "", s.unterminated(line)
+}
+
+// doubleQuoted reads value till the closing quote and decodes escape sequences.
+// Unknown escape sequences are kept as is, so values like "C:\dir" don't need escaping.
+func (s *dotEnvScanner) doubleQuoted() (string, error) {
+	line := s.line
+	s.next()
+	var sb strings.Builder
+	for !s.eof() {
+		c := s.next()
+		switch {
+		case c == '"':
+			return sb.String(), nil
+		case c == '\\' && !s.eof():
+			switch escaped := s.peek(); escaped {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\':
+				sb.WriteByte(escaped)
+			default:
+				sb.WriteByte('\\')
+				continue
+			}
+			s.next()
+		default:
+			sb.WriteByte(c)
+		}
 	}
-	return value
+	return "", s.unterminated(line)
 }
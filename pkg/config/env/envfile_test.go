@@ -2,6 +2,8 @@ package env
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/pechorka/gostdlib/pkg/testing/require"
@@ -101,3 +103,196 @@ BAZ=qux`)
 		os.Unsetenv("VAL")
 	})
 }
+
+func TestParseDotEnv(t *testing.T) {
+	t.Run("grammar", func(t *testing.T) {
+		input := []byte("# header\n" +
+			"export EXPORTED=1\n" +
+			"  SPACED = value with spaces  # comment\n" +
+			"HASH=a#b\n" +
+			"SINGLE='literal \\n ${VAR}' # comment\n" +
+			"DOUBLE=\"tab\\tnew\\nline \\\"quoted\\\" back\\\\slash C:\\dir\"\n" +
+			"PEM=\"-----BEGIN KEY-----\n" +
+			"abc\n" +
+			"-----END KEY-----\"\n" +
+			"MULTI_SINGLE='a\r\nb'\n" +
+			"EMPTY=\n" +
+			"EMPTY_QUOTED=\"\"\n" +
+			"export=word\n" +
+			"CRLF=value\r\n" +
+			"LAST=end")
+		entries, err := ParseDotEnv(input)
+		require.NoError(t, err)
+
+		require.EqualValues(t, DotEnvVars{
+			{Name: "EXPORTED", Value: "1", Line: 2},
+			{Name: "SPACED", Value: "value with spaces", Line: 3},
+			{Name: "HASH", Value: "a#b", Line: 4},
+			{Name: "SINGLE", Value: `literal \n ${VAR}`, Literal: true, Line: 5},
+			{Name: "DOUBLE", Value: "tab\tnew\nline \"quoted\" back\\slash C:\\dir", Line: 6},
+			{Name: "PEM", Value: "-----BEGIN KEY-----\nabc\n-----END KEY-----", Line: 7},
+			{Name: "MULTI_SINGLE", Value: "a\r\nb", Literal: true, Line: 10},
+			{Name: "EMPTY", Value: "", Line: 12},
+			{Name: "EMPTY_QUOTED", Value: "", Line: 13},
+			{Name: "export", Value: "word", Line: 14},
+			{Name: "CRLF", Value: "value", Line: 15},
+			{Name: "LAST", Value: "end", Line: 16},
+		}, entries)
+	})
+
+	t.Run("syntax errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			input string
+			err   string
+		}{
+			{"missing equals", "FOO=bar\nINVALID_LINE\n", "line 2: expected = after INVALID_LINE"},
+			{"invalid name", "FOO=bar\n\n=value\n", `line 3: invalid variable name at "=value"`},
+			{"unterminated double quote", "FOO=\"bar\n\nBAZ=qux", "line 3: unterminated quoted value started at line 1"},
+			{"unterminated single quote", "A=1\nFOO='bar", "line 2: unterminated quoted value"},
+			{"unterminated before trailing newline", "A=1\nFOO='bar\n", "line 2: unterminated quoted value"},
+			{"text after quote", "FOO='bar' baz\n", `line 1: unexpected 'b' after value`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := ParseDotEnv([]byte(tt.input))
+				require.Error(t, err)
+				require.Equal(t, tt.err, err.Error())
+			})
+		}
+	})
+}
+
+func TestReadDotEnv(t *testing.T) {
+	t.Run("map", func(t *testing.T) {
+		t.Parallel()
+
+		values, err := ReadDotEnv(strings.NewReader("A=1\nB='${A}'\nC=\"${A}\"\nD='a$b'\nA=2\n"))
+		require.NoError(t, err)
+		require.EqualValues(t, map[string]string{"A": "2", "B": "${A}", "C": "${A}", "D": "a$b"}, values)
+
+		_, ok := os.LookupEnv("A")
+		require.False(t, ok)
+	})
+
+	t.Run("parse config", func(t *testing.T) {
+		t.Parallel()
+
+		values, err := ReadDotEnv(strings.NewReader("DB_HOST='$literal'\nKAFKA_BROKERS=${DB_HOST},b\n"))
+		require.NoError(t, err)
+
+		cfg := &testConfig{}
+		require.NoError(t, ParseConfigFrom(cfg, MapSource(values)))
+		require.Equal(t, "$literal", cfg.Db.Host)
+		require.EqualValues(t, []string{"$literal", "b"}, cfg.Kafka.Brokers)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ReadDotEnv(strings.NewReader("A=1\nB\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	// tests change working directory and process environment, so they aren't parallel
+	root := t.TempDir()
+	sub := filepath.Join(root, "pkg", "sub")
+	require.NoError(t, os.MkdirAll(sub, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env"), []byte("GOSTDLIB_LOAD_A=root\nGOSTDLIB_LOAD_B=root\nGOSTDLIB_LOAD_URL=http://${GOSTDLIB_LOAD_B}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env.local"), []byte("GOSTDLIB_LOAD_B=local\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".env.test"), []byte("GOSTDLIB_LOAD_C=test\n"), 0o600))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	unset := func() {
+		for _, key := range []string{"GOSTDLIB_LOAD_A", "GOSTDLIB_LOAD_B", "GOSTDLIB_LOAD_C", "GOSTDLIB_LOAD_URL"} {
+			os.Unsetenv(key)
+		}
+	}
+
+	t.Run("layered files", func(t *testing.T) {
+		t.Cleanup(unset)
+		prepareEnv(t, "GOSTDLIB_LOAD_ENV", "test")
+		require.NoError(t, os.Chdir(root))
+
+		err := LoadDotEnv(
+			Files(".env"),
+			OptionalFiles(".env.local", ".env.${GOSTDLIB_LOAD_ENV}", ".env.missing"),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, "root", os.Getenv("GOSTDLIB_LOAD_A"))
+		require.Equal(t, "local", os.Getenv("GOSTDLIB_LOAD_B"))
+		require.Equal(t, "test", os.Getenv("GOSTDLIB_LOAD_C"))
+		require.Equal(t, "http://local", os.Getenv("GOSTDLIB_LOAD_URL"))
+	})
+
+	t.Run("references without braces in paths", func(t *testing.T) {
+		t.Cleanup(unset)
+		prepareEnv(t, "GOSTDLIB_LOAD_ENV", "test")
+		require.NoError(t, os.Chdir(root))
+
+		require.NoError(t, LoadDotEnv(OptionalFiles(".env.$GOSTDLIB_LOAD_ENV")))
+
+		require.Equal(t, "test", os.Getenv("GOSTDLIB_LOAD_C"))
+	})
+
+	t.Run("no override", func(t *testing.T) {
+		t.Cleanup(unset)
+		prepareEnv(t, "GOSTDLIB_LOAD_B", "process")
+		require.NoError(t, os.Chdir(root))
+
+		require.NoError(t, LoadDotEnv(NoOverride()))
+
+		require.Equal(t, "root", os.Getenv("GOSTDLIB_LOAD_A"))
+		require.Equal(t, "process", os.Getenv("GOSTDLIB_LOAD_B"))
+		require.Equal(t, "http://process", os.Getenv("GOSTDLIB_LOAD_URL"))
+	})
+
+	t.Run("override by default", func(t *testing.T) {
+		t.Cleanup(unset)
+		prepareEnv(t, "GOSTDLIB_LOAD_B", "process")
+		require.NoError(t, os.Chdir(root))
+
+		require.NoError(t, ExportDotEnv())
+
+		require.Equal(t, "root", os.Getenv("GOSTDLIB_LOAD_B"))
+	})
+
+	t.Run("required file is missing", func(t *testing.T) {
+		t.Cleanup(unset)
+		require.NoError(t, os.Chdir(sub))
+
+		require.Error(t, LoadDotEnv())
+		require.Error(t, LoadDotEnv(Files(".env.missing"), SearchParents()))
+		require.NoError(t, LoadDotEnv(OptionalFiles(".env")))
+		require.Equal(t, "", os.Getenv("GOSTDLIB_LOAD_A"))
+	})
+
+	t.Run("search parents", func(t *testing.T) {
+		t.Cleanup(unset)
+		require.NoError(t, os.Chdir(sub))
+
+		require.NoError(t, LoadDotEnv(Files(".env"), SearchParents()))
+
+		require.Equal(t, "root", os.Getenv("GOSTDLIB_LOAD_A"))
+	})
+
+	t.Run("reference to overridden value", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("GOSTDLIB_LOAD_PATH=${GOSTDLIB_LOAD_PATH}:/x\nGOSTDLIB_LOAD_FLAGS=a\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env.local"), []byte("GOSTDLIB_LOAD_FLAGS=${GOSTDLIB_LOAD_FLAGS},b\nGOSTDLIB_LOAD_FLAGS=${GOSTDLIB_LOAD_FLAGS},c\n"), 0o600))
+		prepareEnv(t, "GOSTDLIB_LOAD_PATH", "/bin")
+		t.Cleanup(func() { os.Unsetenv("GOSTDLIB_LOAD_FLAGS") })
+		require.NoError(t, os.Chdir(dir))
+
+		require.NoError(t, LoadDotEnv(Files(".env", ".env.local")))
+
+		require.Equal(t, "/bin:/x", os.Getenv("GOSTDLIB_LOAD_PATH"))
+		require.Equal(t, "a,b,c", os.Getenv("GOSTDLIB_LOAD_FLAGS"))
+	})
+}
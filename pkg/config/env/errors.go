@@ -0,0 +1,74 @@
+package env
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+var (
+	// ErrRequired is reported for fields with `required:"true"` tag that have no value
+	ErrRequired = errs.New("required environment variable is not set")
+	// ErrUnsupportedType is reported for fields which type can't be parsed from env value
+	ErrUnsupportedType = errs.New("unsupported type")
+)
+
+// FieldError describes failure of a single config field.
+type FieldError struct {
+	Field string // Go path of the field, e.g. Db.Host
+	Key   string // env key of the field, e.g. DB_HOST
+	Value string // raw value that failed to parse, empty for missing values
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Key, e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned by ParseConfig and contains errors of all failed fields.
+// Underlying errors can be checked with errs.Is and errs.As:
+//
+//	var fieldErr *env.FieldError
+//	if errs.As(err, &fieldErr) { ... }
+//	if errs.Is(err, env.ErrRequired) { ... }
+type ConfigError struct {
+	Errors []*FieldError
+}
+
+// Error renders all field errors as a table:
+//
+//	config has 2 errors:
+//	  KEY      FIELD    VALUE      ERROR
+//	  DB_HOST  Db.Host  ""         required environment variable is not set
+//	  DB_PORT  Db.Port  "invalid"  failed to parse int value: ...
+func (e *ConfigError) Error() string {
+	var sb strings.Builder
+	if len(e.Errors) == 1 {
+		sb.WriteString("config has 1 error:\n")
+	} else {
+		fmt.Fprintf(&sb, "config has %d errors:\n", len(e.Errors))
+	}
+
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  KEY\tFIELD\tVALUE\tERROR")
+	for _, fieldErr := range e.Errors {
+		fmt.Fprintf(tw, "  %s\t%s\t%q\t%v\n", fieldErr.Key, fieldErr.Field, fieldErr.Value, fieldErr.Err)
+	}
+	tw.Flush()
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (e *ConfigError) Unwrap() []error {
+	unwrapped := make([]error, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		unwrapped = append(unwrapped, fieldErr)
+	}
+	return unwrapped
+}
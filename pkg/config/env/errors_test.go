@@ -0,0 +1,60 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+func TestConfigError(t *testing.T) {
+	t.Run("all failed fields are reported", func(t *testing.T) {
+		prepareEnv(t,
+			"DB_PORT", "invalid",
+			"TIMEOUT", "invalid",
+		)
+
+		cfg := &struct {
+			Base testConfig `inline:"true"`
+			Ch   chan int   `env:"CH"`
+		}{}
+		err := ParseConfig(cfg)
+		require.Error(t, err)
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 4, len(configErr.Errors))
+
+		require.Equal(t, "DB_HOST", configErr.Errors[0].Key)
+		require.ErrorIs(t, configErr.Errors[0], ErrRequired)
+
+		require.Equal(t, "DB_PORT", configErr.Errors[1].Key)
+		require.Equal(t, "Base.Db.Port", configErr.Errors[1].Field)
+		require.Equal(t, "invalid", configErr.Errors[1].Value)
+
+		require.Equal(t, "TIMEOUT", configErr.Errors[2].Key)
+		require.Equal(t, "invalid", configErr.Errors[2].Value)
+
+		require.Equal(t, "CH", configErr.Errors[3].Key)
+		require.ErrorIs(t, configErr.Errors[3], ErrUnsupportedType)
+	})
+
+	t.Run("errors are rendered as table", func(t *testing.T) {
+		err := &ConfigError{Errors: []*FieldError{
+			{Field: "Db.Host", Key: "DB_HOST", Err: ErrRequired},
+			{Field: "Db.Port", Key: "DB_PORT", Value: "invalid", Err: errs.New("failed to parse int value")},
+		}}
+
+		expected := `config has 2 errors:
+  KEY      FIELD    VALUE      ERROR
+  DB_HOST  Db.Host  ""         required environment variable is not set
+  DB_PORT  Db.Port  "invalid"  failed to parse int value`
+		require.Equal(t, expected, err.Error())
+	})
+
+	t.Run("field error", func(t *testing.T) {
+		err := &FieldError{Field: "Db.Host", Key: "DB_HOST", Err: ErrRequired}
+		require.Equal(t, "DB_HOST (Db.Host): required environment variable is not set", err.Error())
+		require.ErrorIs(t, err, ErrRequired)
+	})
+}
@@ -0,0 +1,197 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// ErrExpand is reported for values that reference unset variables with ${VAR:?message}
+// or form a reference cycle.
+var ErrExpand = errs.New("failed to expand value")
+
+// expander replaces references to other variables in values:
+//
+//	${VAR}          - value of VAR, empty if VAR is unset
+//	${VAR:-default} - value of VAR, default if VAR is unset or empty
+//	${VAR:?message} - value of VAR, error with message if VAR is unset or empty
+//	$$              - literal $
+//
+// Other $ characters are kept as is. Values of referenced variables are expanded recursively.
+type expander struct {
+	lookup func(key string) (string, bool)
+	// previous returns n-th earlier definition of the key, so a variable can reference the value it overrides:
+	// PATH=${PATH}:/x. Without it such reference is a cycle.
+	previous func(key string, n int) (string, bool)
+	// bare allows references without braces: $VAR. It's used for file paths like .env.$APP_ENV,
+	// values keep $ as is to not break passwords.
+	bare bool
+}
+
+// ExpandingSource is implemented by sources which values can reference other variables, see expander.
+// Values of other sources, like process environment and flags already expanded by the shell, are used as is.
+type ExpandingSource interface {
+	Expands(key string) bool
+}
+
+// sourceExpands reports whether value of the key in source should be expanded.
+func sourceExpands(source Source, key string) bool {
+	if s, ok := source.(ExpandingSource); ok {
+		return s.Expands(key)
+	}
+	return false
+}
+
+// fieldExpands reports whether value of the field read from ExpandingSource is expanded.
+// Secrets are expanded only with `expand:"true"` tag, so values like pa$$word are kept as is.
+func fieldExpands(field reflect.StructField) bool {
+	switch field.Tag.Get("expand") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return !isSecret(field)
+}
+
+// expand expands value of the variable key.
+func (e *expander) expand(key, value string) (string, error) {
+	return e.expandValue(value, []string{key})
+}
+
+// expandValue expands value, stack holds variables which values are being expanded to detect cycles.
+func (e *expander) expandValue(value string, stack []string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(value))
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			sb.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			i++
+		case '{':
+			end := matchingBrace(value, i+1)
+			if end == -1 {
+				return "", errs.Newf("%w: unterminated reference in %q", ErrExpand, value)
+			}
+			expanded, err := e.expandReference(value[i+2:end], stack)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(expanded)
+			i = end
+		default:
+			end := i + 1
+			for e.bare && end < len(value) && isNameByte(value[end], end == i+1) {
+				end++
+			}
+			if end == i+1 {
+				sb.WriteByte('$')
+				continue
+			}
+			expanded, err := e.resolve(value[i+1:end], stack)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(expanded)
+			i = end - 1
+		}
+	}
+	return sb.String(), nil
+}
+
+// isNameByte reports whether c can be a part of variable name, names don't start with digits.
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// expandReference expands content of ${...}.
+func (e *expander) expandReference(ref string, stack []string) (string, error) {
+	name, op, arg := ref, "", ""
+	if i := strings.IndexByte(ref, ':'); i != -1 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	if name == "" {
+		return "", errs.Newf("%w: empty variable name in ${%s}", ErrExpand, ref)
+	}
+
+	value, err := e.resolve(name, stack)
+	if err != nil || value != "" {
+		return value, err
+	}
+
+	switch op {
+	case ":-":
+		return e.expandValue(arg, stack)
+	case ":?":
+		message, err := e.expandValue(arg, stack)
+		if err != nil {
+			return "", err
+		}
+		if message == "" {
+			message = "variable is not set"
+		}
+		return "", errs.Newf("%w: %s: %s", ErrExpand, name, message)
+	}
+	return "", nil
+}
+
+// resolve returns expanded value of the variable.
+func (e *expander) resolve(name string, stack []string) (string, error) {
+	if n := overrides(stack, name); n > 0 && e.previous != nil {
+		value, ok := e.previous(name, n)
+		if !ok {
+			return "", nil
+		}
+		return e.expandValue(value, append(stack[:len(stack):len(stack)], name))
+	}
+
+	for i, key := range stack {
+		if key == name {
+			cycle := append(stack[i:len(stack):len(stack)], name)
+			return "", errs.Newf("%w: reference cycle %s", ErrExpand, strings.Join(cycle, " -> "))
+		}
+	}
+
+	value, ok := e.lookup(name)
+	if !ok {
+		return "", nil
+	}
+	return e.expandValue(value, append(stack[:len(stack):len(stack)], name))
+}
+
+// overrides returns how many definitions of the variable are expanded at the top of the stack,
+// so reference to it from the n-th one resolves to n-th earlier definition.
+func overrides(stack []string, name string) int {
+	n := 0
+	for i := len(stack) - 1; i >= 0 && stack[i] == name; i-- {
+		n++
+	}
+	return n
+}
+
+// matchingBrace returns index of '}' which closes '{' at index start, or -1.
+func matchingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
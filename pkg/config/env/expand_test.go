@@ -0,0 +1,200 @@
+package env
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+func Test_expander(t *testing.T) {
+	vars := MapSource{
+		"HOST":  "localhost",
+		"PORT":  "5432",
+		"URL":   "postgres://${HOST}:${PORT}/app",
+		"EMPTY": "",
+		"SELF":  "${SELF}",
+		"A":     "${B}",
+		"B":     "${C:-${A}}",
+	}
+	e := expander{lookup: vars.Lookup}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "no references", value: "plain", expected: "plain"},
+		{name: "reference", value: "${HOST}:${PORT}", expected: "localhost:5432"},
+		{name: "recursive reference", value: "${URL}?sslmode=disable", expected: "postgres://localhost:5432/app?sslmode=disable"},
+		{name: "unset reference", value: "[${MISSING}]", expected: "[]"},
+		{name: "default for unset", value: "${MISSING:-fallback}", expected: "fallback"},
+		{name: "default for empty", value: "${EMPTY:-fallback}", expected: "fallback"},
+		{name: "default is not used for set", value: "${HOST:-fallback}", expected: "localhost"},
+		{name: "nested default", value: "${MISSING:-${HOST}}", expected: "localhost"},
+		{name: "escaped dollar", value: "$${HOST} costs $$5", expected: "${HOST} costs $5"},
+		{name: "lone dollar", value: "pa$word$", expected: "pa$word$"},
+		{name: "required set", value: "${HOST:?host is required}", expected: "localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := e.expand("VALUE", tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, expanded)
+		})
+	}
+
+	errorTests := []struct {
+		name    string
+		value   string
+		message string
+	}{
+		{name: "required unset", value: "${MISSING:?must be set}", message: "failed to expand value: MISSING: must be set"},
+		{name: "required empty", value: "${EMPTY:?}", message: "failed to expand value: EMPTY: variable is not set"},
+		{name: "self reference", value: "${SELF}", message: "failed to expand value: reference cycle SELF -> SELF"},
+		{name: "indirect cycle", value: "${A}", message: "failed to expand value: reference cycle A -> B -> A"},
+		{name: "reference to itself", value: "${VALUE}", message: "failed to expand value: reference cycle VALUE -> VALUE"},
+		{name: "unterminated", value: "${HOST", message: `failed to expand value: unterminated reference in "${HOST"`},
+		{name: "empty name", value: "${}", message: "failed to expand value: empty variable name in ${}"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.expand("VALUE", tt.value)
+			require.ErrorIs(t, err, ErrExpand)
+			require.Equal(t, tt.message, err.Error())
+		})
+	}
+}
+
+func Test_expanderBare(t *testing.T) {
+	e := expander{lookup: MapSource{"APP_ENV": "prod", "DIR": "/etc"}.Lookup, bare: true}
+
+	expanded, err := e.expand("PATH", "$DIR/.env.$APP_ENV, ${APP_ENV}_1 $1 $$APP_ENV $")
+	require.NoError(t, err)
+	require.Equal(t, "/etc/.env.prod, prod_1 $1 $APP_ENV $", expanded)
+}
+
+func Test_expanderPrevious(t *testing.T) {
+	definitions := []string{"a", "${FLAGS},b", "${FLAGS},c"}
+	e := expander{
+		lookup: MapSource{"FLAGS": definitions[2], "OTHER": "${FLAGS}"}.Lookup,
+		previous: func(key string, n int) (string, bool) {
+			if i := len(definitions) - 1 - n; key == "FLAGS" && i >= 0 {
+				return definitions[i], true
+			}
+			return "", false
+		},
+	}
+
+	expanded, err := e.expand("FLAGS", definitions[2])
+	require.NoError(t, err)
+	require.Equal(t, "a,b,c", expanded)
+
+	_, err = e.expand("FLAGS", "${OTHER}")
+	require.ErrorIs(t, err, ErrExpand)
+	require.Equal(t, "failed to expand value: reference cycle FLAGS -> OTHER -> FLAGS", err.Error())
+}
+
+func TestParseConfig_Expand(t *testing.T) {
+	t.Run("values and defaults are expanded", func(t *testing.T) {
+		cfg := &struct {
+			Host    string `env:"HOST"`
+			URL     string `env:"URL"`
+			Backup  string `env:"BACKUP" default:"${HOST}:6000"`
+			Literal string `env:"LITERAL" expand:"false"`
+		}{}
+		err := ParseConfigFrom(cfg,
+			MapSource{"HOST": "db", "URL": "postgres://${HOST}:${PORT}/app", "LITERAL": "${HOST}"},
+			MapSource{"PORT": "5432"},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, "postgres://db:5432/app", cfg.URL)
+		require.Equal(t, "db:6000", cfg.Backup)
+		require.Equal(t, "${HOST}", cfg.Literal)
+	})
+
+	t.Run("expansion errors are reported", func(t *testing.T) {
+		cfg := &struct {
+			A string `env:"A"`
+			B string `env:"B"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"A": "${B}", "B": "${A}"})
+		require.ErrorIs(t, err, ErrExpand)
+	})
+}
+
+func TestParseConfig_ExpandSources(t *testing.T) {
+	t.Run("process environment is used as is", func(t *testing.T) {
+		prepareEnv(t, "GOSTDLIB_EXPAND_RAW", "pa$$word${X}")
+
+		cfg := &struct {
+			Raw    string `env:"GOSTDLIB_EXPAND_RAW"`
+			Ref    string `env:"REF"`
+			Forced string `env:"FORCED" expand:"true"`
+		}{}
+		err := ParseConfigFrom(cfg,
+			OSSource{},
+			MapSource{"REF": "${GOSTDLIB_EXPAND_RAW}", "FORCED": "$${X}"},
+		)
+		require.NoError(t, err)
+		require.Equal(t, "pa$$word${X}", cfg.Raw)
+		require.Equal(t, "pa$$word${X}", cfg.Ref)
+		require.Equal(t, "${X}", cfg.Forced)
+	})
+
+	t.Run("expand tag forces expansion of process environment", func(t *testing.T) {
+		prepareEnv(t, "GOSTDLIB_EXPAND_FORCED", "${GOSTDLIB_EXPAND_HOST}:80")
+		prepareEnv(t, "GOSTDLIB_EXPAND_HOST", "db")
+
+		cfg := &struct {
+			Addr string `env:"GOSTDLIB_EXPAND_FORCED" expand:"true"`
+		}{}
+		require.NoError(t, ParseConfigFrom(cfg, OSSource{}))
+		require.Equal(t, "db:80", cfg.Addr)
+	})
+
+	t.Run("secrets are not expanded", func(t *testing.T) {
+		cfg := &struct {
+			Password string         `env:"PASSWORD" secret:"true"`
+			Token    Secret[string] `env:"TOKEN" default:"$${T}"`
+		}{}
+		require.NoError(t, ParseConfigFrom(cfg, MapSource{"PASSWORD": "pa$$word${X}"}))
+		require.Equal(t, "pa$$word${X}", cfg.Password)
+		require.Equal(t, "$${T}", cfg.Token.Value())
+
+		values, err := Marshal(cfg)
+		require.NoError(t, err)
+		require.Equal(t, "pa$$word${X}", values["PASSWORD"])
+	})
+
+	t.Run("expansion errors of secrets are redacted", func(t *testing.T) {
+		cfg := &struct {
+			Password string `env:"PASSWORD" secret:"true" expand:"true"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"PASSWORD": `s"3cr3t${y`})
+		require.ErrorIs(t, err, ErrExpand)
+		require.False(t, strings.Contains(err.Error(), "3cr3t"))
+	})
+}
+
+func Test_exportDotEnv_Expand(t *testing.T) {
+	prepareEnv(t, "GOSTDLIB_EXPAND_HOST", "envhost")
+	t.Cleanup(func() {
+		for _, key := range []string{"GOSTDLIB_EXPAND_PORT", "GOSTDLIB_EXPAND_URL", "GOSTDLIB_EXPAND_LITERAL", "GOSTDLIB_EXPAND_FORWARD"} {
+			os.Unsetenv(key)
+		}
+	})
+
+	input := []byte(`GOSTDLIB_EXPAND_URL="http://${GOSTDLIB_EXPAND_HOST}:${GOSTDLIB_EXPAND_PORT}"
+GOSTDLIB_EXPAND_PORT=8080
+GOSTDLIB_EXPAND_LITERAL='${GOSTDLIB_EXPAND_HOST}'
+GOSTDLIB_EXPAND_FORWARD=${GOSTDLIB_EXPAND_LITERAL}`)
+	err := exportDotEnv(input)
+	require.NoError(t, err)
+
+	require.Equal(t, "http://envhost:8080", os.Getenv("GOSTDLIB_EXPAND_URL"))
+	require.Equal(t, "${GOSTDLIB_EXPAND_HOST}", os.Getenv("GOSTDLIB_EXPAND_LITERAL"))
+	require.Equal(t, "${GOSTDLIB_EXPAND_HOST}", os.Getenv("GOSTDLIB_EXPAND_FORWARD"))
+}
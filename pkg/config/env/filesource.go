@@ -0,0 +1,400 @@
+package env
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// JSON and INI files are flattened to the keys parser reads, so they fill the same tagged struct:
+//
+//	{"db": {"host": "localhost", "pool": {"max": 20}}, "brokers": ["a:9092", "b:9092"]}
+//
+//	[db]
+//	host = "localhost"
+//	pool.max = 20
+//
+// both are DB_HOST=localhost, DB_POOL_MAX=20. Names are converted like field names, see KeyNaming,
+// "-" in names is replaced with "_". Arrays of scalars are joined with `sep` of the field, comma by default,
+// arrays of objects and [[tables]] are read by indexes: UPSTREAMS_0_HOST. Maps of scalars must be written
+// as strings or arrays: "a:1,b:2" or ["a:1", "b:2"].
+// Values can reference other variables with ${VAR}, except single-quoted INI values, which are literal.
+//
+// FromFiles layers files under process environment:
+//
+//	err := env.ParseConfig(&cfg, env.FromFiles("config.json", "config.local.ini"))
+
+// FromFiles adds files to look values up after sources, process environment by default.
+// Earlier files take precedence over later ones. Format is chosen by extension:
+// .json for JSONSource, .ini, .toml, .conf and .cfg for INISource, .env for DotEnvSource.
+// YAML isn't supported, its grammar is too large to implement without dependencies: convert such files to JSON.
+// Keys of .env files are used as is, keys of other files get KeyPrefix and KeySeparator.
+func FromFiles(paths ...string) Option {
+	return func(o *options) {
+		o.files = append(o.files, paths...)
+	}
+}
+
+// JSONSource reads JSON file with top-level object, only naming options like KeyPrefix are used from opts.
+func JSONSource(path string, opts ...Option) (*FileSource, error) {
+	return readFileSource(path, newOptions(opts).naming, parseJSONSource)
+}
+
+// INISource reads INI file or a subset of TOML: sections, [[arrays of tables]], dotted keys,
+// quoted strings, numbers, booleans and single-line arrays. Comments start with # or ;.
+// Only naming options like KeyPrefix are used from opts.
+func INISource(path string, opts ...Option) (*FileSource, error) {
+	return readFileSource(path, newOptions(opts).naming, parseINISource)
+}
+
+// openFileSource reads file at path in the format chosen by extension.
+func openFileSource(path string, n naming) (Source, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return readFileSource(path, n, parseJSONSource)
+	case ".ini", ".toml", ".conf", ".cfg":
+		return readFileSource(path, n, parseINISource)
+	case ".env":
+		return DotEnvSource(path)
+	}
+	return nil, errs.Newf("unsupported format of %s", path)
+}
+
+func readFileSource(path string, n naming, parse func([]byte, naming, *FileSource) error) (*FileSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to read %s", path)
+	}
+
+	source := &FileSource{Path: path, Values: make(MapSource), Lines: make(map[string]int), Lists: make(map[string][]string)}
+	if err := parse(data, n, source); err != nil {
+		return nil, errs.Wrapf(err, "failed to parse %s", path)
+	}
+	return source, nil
+}
+
+// fileKey joins name from the file to prefix, converting it like a field name.
+func (n naming) fileKey(prefix, name string) string {
+	return n.join(prefix, n.convert(strings.ReplaceAll(name, "-", "_")))
+}
+
+func (f *FileSource) set(key, value string, line int) {
+	f.Values[key] = value
+	f.Lines[key] = line
+}
+
+// setList sets elements of array of scalars, value of the key is elements joined with comma.
+func (f *FileSource) setList(key string, elems []string, line int) {
+	f.set(key, joinEscaped(elems, defaultSep), line)
+	f.Lists[key] = elems
+}
+
+// jsonFlattener walks tokens of JSON document to remember lines of the keys.
+type jsonFlattener struct {
+	dec    *json.Decoder
+	data   []byte
+	naming naming
+	source *FileSource
+}
+
+func parseJSONSource(data []byte, n naming, source *FileSource) error {
+	f := &jsonFlattener{dec: json.NewDecoder(bytes.NewReader(data)), data: data, naming: n, source: source}
+	f.dec.UseNumber()
+
+	err := f.document()
+	var syntaxErr *json.SyntaxError
+	if errs.As(err, &syntaxErr) {
+		return errs.Newf("line %d: %s", f.lineAt(syntaxErr.Offset), syntaxErr.Error())
+	}
+	return err
+}
+
+func (f *jsonFlattener) document() error {
+	tok, err := f.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return errs.New("top-level value must be an object")
+	}
+	if err := f.object(""); err != nil {
+		return err
+	}
+	if _, err := f.dec.Token(); err != io.EOF {
+		return errs.Newf("line %d: unexpected data after top-level object", f.lineAt(f.dec.InputOffset()))
+	}
+	return nil
+}
+
+// object flattens members of the object, opening brace is already read.
+func (f *jsonFlattener) object(prefix string) error {
+	for f.dec.More() {
+		tok, err := f.dec.Token()
+		if err != nil {
+			return err
+		}
+		key := f.naming.fileKey(prefix, tok.(string)) // keys of objects are always strings
+		line := f.lineAt(f.dec.InputOffset())
+
+		value, scalar, err := f.value(key, line)
+		if err != nil {
+			return err
+		}
+		if scalar {
+			f.source.set(key, value, line)
+		}
+	}
+	_, err := f.dec.Token() // closing brace
+	return err
+}
+
+// value flattens objects and arrays of objects to keys under key and returns scalar values to the caller.
+// null isn't a scalar, so its key stays unset.
+func (f *jsonFlattener) value(key string, line int) (string, bool, error) {
+	tok, err := f.dec.Token()
+	if err != nil {
+		return "", false, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			return "", false, f.object(key)
+		}
+		return f.array(key, line)
+	case string:
+		return tok, true, nil
+	case json.Number:
+		return tok.String(), true, nil
+	case bool:
+		return strconv.FormatBool(tok), true, nil
+	}
+	return "", false, nil
+}
+
+// array sets elements of array of scalars as a list, see FileSource.Lists.
+// Elements of other arrays are set by indexes: KEY_0, KEY_1.
+func (f *jsonFlattener) array(key string, line int) (string, bool, error) {
+	var values []string
+	var scalars []bool
+	allScalars := true
+	for i := 0; f.dec.More(); i++ {
+		value, scalar, err := f.value(f.naming.join(key, strconv.Itoa(i)), line)
+		if err != nil {
+			return "", false, err
+		}
+		values = append(values, value)
+		scalars = append(scalars, scalar)
+		allScalars = allScalars && scalar
+	}
+	if _, err := f.dec.Token(); err != nil { // closing bracket
+		return "", false, err
+	}
+
+	if allScalars {
+		f.source.setList(key, values, line)
+		return "", false, nil
+	}
+	for i, value := range values {
+		if scalars[i] {
+			f.source.set(f.naming.join(key, strconv.Itoa(i)), value, line)
+		}
+	}
+	return "", false, nil
+}
+
+func (f *jsonFlattener) lineAt(offset int64) int {
+	return 1 + bytes.Count(f.data[:min(offset, int64(len(f.data)))], []byte("\n"))
+}
+
+func parseINISource(data []byte, n naming, source *FileSource) error {
+	section := ""
+	tables := make(map[string]int) // number of [[table]] headers seen by key
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			name, err := iniHeader(line[2:], "]]")
+			if err != nil {
+				return errs.Wrapf(err, "line %d", lineNo)
+			}
+			table := iniKey(n, "", name)
+			section = n.join(table, strconv.Itoa(tables[table]))
+			tables[table]++
+			continue
+		}
+		if line[0] == '[' {
+			name, err := iniHeader(line[1:], "]")
+			if err != nil {
+				return errs.Wrapf(err, "line %d", lineNo)
+			}
+			section = iniKey(n, "", name)
+			continue
+		}
+
+		name, raw, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return errs.Newf("line %d: expected key = value", lineNo)
+		}
+		value, elems, err := parseINIValue(strings.TrimSpace(raw))
+		if err != nil {
+			return errs.Wrapf(err, "line %d", lineNo)
+		}
+		if elems != nil {
+			source.setList(iniKey(n, section, name), elems, lineNo)
+			continue
+		}
+		source.set(iniKey(n, section, name), value, lineNo)
+	}
+	return nil
+}
+
+// iniHeader returns name of the section header, opening bracket is already cut.
+func iniHeader(s, closing string) (string, error) {
+	name, rest, ok := strings.Cut(s, closing)
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", errs.New("invalid section header")
+	}
+	if !isINIComment(strings.TrimSpace(rest)) {
+		return "", errs.Newf("unexpected %q after section header", rest)
+	}
+	return name, nil
+}
+
+// iniKey joins parts of dotted name like pool.max to prefix.
+func iniKey(n naming, prefix, name string) string {
+	key := prefix
+	for _, part := range strings.Split(name, ".") {
+		key = n.fileKey(key, strings.Trim(strings.TrimSpace(part), `"`))
+	}
+	return key
+}
+
+func isINIComment(s string) bool {
+	return s == "" || s[0] == '#' || s[0] == ';'
+}
+
+// parseINIValue returns value in the syntax of sources: single-quoted values are escaped to stay literal.
+// Elements are returned instead of value for arrays.
+func parseINIValue(raw string) (string, []string, error) {
+	if strings.HasPrefix(raw, `"""`) || strings.HasPrefix(raw, "'''") {
+		return "", nil, errs.New("multi-line strings are not supported")
+	}
+
+	var value, rest string
+	var elems []string
+	var err error
+	switch {
+	case raw == "":
+		return "", nil, nil
+	case raw[0] == '"' || raw[0] == '\'':
+		value, rest, err = cutINIString(raw)
+	case raw[0] == '[':
+		elems, rest, err = cutINIArray(raw)
+	default:
+		return cutINIComment(raw), nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !isINIComment(strings.TrimSpace(rest)) {
+		return "", nil, errs.Newf("unexpected %q after value", rest)
+	}
+	return value, elems, nil
+}
+
+// cutINIComment removes comment, which starts with # or ; after whitespace, from unquoted value.
+func cutINIComment(raw string) string {
+	for i := 1; i < len(raw); i++ {
+		if (raw[i] == '#' || raw[i] == ';') && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			return strings.TrimSpace(raw[:i])
+		}
+	}
+	return raw
+}
+
+// cutINIString returns quoted string at the start of s and the rest of s.
+// Double-quoted strings support \n, \r, \t, \" and \\ escapes, other escapes are kept as is.
+func cutINIString(s string) (string, string, error) {
+	quote := s[0]
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			value := sb.String()
+			if quote == '\'' {
+				value = strings.ReplaceAll(value, "$", "$$")
+			}
+			return value, s[i+1:], nil
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", "", errs.New("unterminated string")
+}
+
+// cutINIArray returns elements of single-line array at the start of s and the rest of s.
+func cutINIArray(s string) ([]string, string, error) {
+	elems := []string{}
+	rest := strings.TrimSpace(s[1:])
+	for {
+		if rest == "" {
+			return nil, "", errs.New("unterminated array")
+		}
+		if rest[0] == ']' {
+			return elems, rest[1:], nil
+		}
+
+		var elem string
+		switch rest[0] {
+		case '"', '\'':
+			var err error
+			elem, rest, err = cutINIString(rest)
+			if err != nil {
+				return nil, "", err
+			}
+		case '[', '{':
+			return nil, "", errs.New("nested arrays and inline tables are not supported")
+		default:
+			end := strings.IndexAny(rest, ",]")
+			if end < 0 {
+				return nil, "", errs.New("unterminated array")
+			}
+			elem, rest = strings.TrimSpace(rest[:end]), rest[end:]
+		}
+		elems = append(elems, elem)
+
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, "", errs.New("expected , or ] in array")
+		}
+	}
+}
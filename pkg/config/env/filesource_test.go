@@ -0,0 +1,206 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type fileConfig struct {
+	Db struct {
+		Host string `env:"HOST" required:"true"`
+		Port int    `env:"PORT" default:"5432"`
+		Pool struct {
+			Max int `env:"MAX"`
+		} `env:"POOL"`
+	} `env:"DB"`
+	Brokers   []string `env:"BROKERS"`
+	Debug     bool     `env:"DEBUG"`
+	LogLevel  string   `env:"LOG_LEVEL" default:"info"`
+	Upstreams []struct {
+		Host string `env:"HOST"`
+	} `env:"UPSTREAMS"`
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestJSONSource(t *testing.T) {
+	t.Run("nested objects and arrays are flattened", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{
+	"db": {"host": "localhost", "pool": {"max": 20}},
+	"brokers": ["a:9092", "b,c"],
+	"debug": true,
+	"log-level": null,
+	"upstreams": [{"host": "u1"}, {"host": "u2"}]
+}`)
+		source, err := JSONSource(path)
+		require.NoError(t, err)
+		require.EqualValues(t, MapSource{
+			"DB_HOST":          "localhost",
+			"DB_POOL_MAX":      "20",
+			"BROKERS":          `a:9092,b\,c`,
+			"DEBUG":            "true",
+			"UPSTREAMS_0_HOST": "u1",
+			"UPSTREAMS_1_HOST": "u2",
+		}, source.Values)
+		require.Equal(t, path+":2", source.Origin("DB_POOL_MAX"))
+		require.Equal(t, path+":3", source.Origin("BROKERS"))
+
+		cfg := &fileConfig{}
+		require.NoError(t, ParseConfigFrom(cfg, source))
+		require.Equal(t, 20, cfg.Db.Pool.Max)
+		require.EqualValues(t, []string{"a:9092", "b,c"}, cfg.Brokers)
+		require.Equal(t, "info", cfg.LogLevel)
+		require.Equal(t, 2, len(cfg.Upstreams))
+	})
+
+	t.Run("naming options", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{"maxConns": 5, "db": {"host": "localhost"}}`)
+		source, err := JSONSource(path, KeyPrefix("MYSVC_"), KeyNaming(ScreamingSnake))
+		require.NoError(t, err)
+		require.EqualValues(t, MapSource{"MYSVC_MAX_CONNS": "5", "MYSVC_DB_HOST": "localhost"}, source.Values)
+	})
+
+	t.Run("syntax error reports line", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", "{\n\"db\": {\"host\": }\n}")
+		_, err := JSONSource(path)
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "line 2"))
+	})
+
+	t.Run("top-level value must be an object", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `["a"]`)
+		_, err := JSONSource(path)
+		require.Error(t, err)
+	})
+}
+
+func TestINISource(t *testing.T) {
+	t.Run("sections, dotted keys and arrays of tables", func(t *testing.T) {
+		path := writeConfigFile(t, "config.toml", `# comment
+debug = true ; inline comment
+brokers = ["a:9092", 'b,c', d]
+
+[db]
+host = "local\thost"
+pool.max = 20
+port = '${PORT}'
+
+[[upstreams]]
+host = u1
+
+[[upstreams]]
+host = "u2" # comment
+`)
+		source, err := INISource(path)
+		require.NoError(t, err)
+		require.EqualValues(t, MapSource{
+			"DEBUG":            "true",
+			"BROKERS":          `a:9092,b\,c,d`,
+			"DB_HOST":          "local\thost",
+			"DB_POOL_MAX":      "20",
+			"DB_PORT":          "$${PORT}",
+			"UPSTREAMS_0_HOST": "u1",
+			"UPSTREAMS_1_HOST": "u2",
+		}, source.Values)
+		require.Equal(t, path+":7", source.Origin("DB_POOL_MAX"))
+	})
+
+	t.Run("invalid lines", func(t *testing.T) {
+		tests := map[string]string{
+			"missing equals":      "[db]\nhost\n",
+			"unterminated string": `host = "localhost`,
+			"unterminated array":  `brokers = ["a"`,
+			"nested array":        `brokers = [["a"]]`,
+			"multi-line string":   `host = """a`,
+			"invalid header":      "[db",
+			"data after value":    `host = "a" b`,
+		}
+		for name, content := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := INISource(writeConfigFile(t, "config.ini", content))
+				require.Error(t, err)
+				require.True(t, strings.Contains(err.Error(), "line"))
+			})
+		}
+	})
+}
+
+func TestParseConfig_FromFiles(t *testing.T) {
+	t.Run("env overrides files, earlier files override later ones", func(t *testing.T) {
+		jsonPath := writeConfigFile(t, "config.json", `{"db": {"host": "json", "port": 1}, "log_level": "warn"}`)
+		iniPath := writeConfigFile(t, "config.ini", "[db]\nhost = ini\nport = 2\npool.max = 3\n")
+
+		cfg := &fileConfig{}
+		err := ParseConfig(cfg,
+			FromSources(MapSource{"DB_HOST": "env"}),
+			FromFiles(jsonPath, iniPath),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "env", cfg.Db.Host)
+		require.Equal(t, 1, cfg.Db.Port)
+		require.Equal(t, 3, cfg.Db.Pool.Max)
+		require.Equal(t, "warn", cfg.LogLevel)
+	})
+
+	t.Run("files get key prefix", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{"db": {"host": "json"}}`)
+
+		var report Provenance
+		cfg := &fileConfig{}
+		err := ParseConfig(cfg,
+			FromSources(MapSource{}),
+			FromFiles(path),
+			KeyPrefix("MYSVC_"),
+			WithProvenance(&report),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "json", cfg.Db.Host)
+
+		origin, ok := report.Lookup("Db.Host")
+		require.True(t, ok)
+		require.Equal(t, path+":1", origin.Source)
+	})
+
+	t.Run("values reference other sources", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{"db": {"host": "${HOST}"}}`)
+
+		cfg := &fileConfig{}
+		err := ParseConfig(cfg, FromSources(MapSource{"HOST": "localhost"}), FromFiles(path))
+		require.NoError(t, err)
+		require.Equal(t, "localhost", cfg.Db.Host)
+	})
+
+	t.Run("arrays are joined with separator of the field", func(t *testing.T) {
+		jsonPath := writeConfigFile(t, "config.json", `{"hosts": ["a", "b;c", "d,e"]}`)
+		iniPath := writeConfigFile(t, "config.ini", `ports = [80, 443]`)
+
+		var cfg struct {
+			Hosts []string `env:"HOSTS" sep:";"`
+			Ports []int    `env:"PORTS" sep:"|"`
+		}
+		err := ParseConfig(&cfg, FromSources(MapSource{}), FromFiles(jsonPath, iniPath))
+		require.NoError(t, err)
+		require.EqualValues(t, []string{"a", "b;c", "d,e"}, cfg.Hosts)
+		require.EqualValues(t, []int{80, 443}, cfg.Ports)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		path := writeConfigFile(t, "config.yaml", "db: {}")
+		err := ParseConfig(&fileConfig{}, FromFiles(path))
+		require.Error(t, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := ParseConfig(&fileConfig{}, FromFiles(filepath.Join(t.TempDir(), "config.json")))
+		require.Error(t, err)
+	})
+}
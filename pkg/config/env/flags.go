@@ -0,0 +1,137 @@
+package env
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// FlagSource provides values of command-line flags registered by RegisterFlags.
+// Only flags set on the command line are present, so it should be the first source
+// to let flags override env, which overrides defaults:
+//
+//	flags, err := env.RegisterFlags(flag.CommandLine, (*Config)(nil))
+//	if err != nil {
+//		return err
+//	}
+//	flag.Parse()
+//	err = env.ParseConfig(&cfg, env.FromSources(flags, env.OSSource{}))
+type FlagSource struct {
+	names  map[string]string // key to flag name
+	values map[string]string // key to value of the set flag
+}
+
+// RegisterFlags registers a flag on fs for every key of the config: --db-host for DB_HOST.
+// Usage of the flag is taken from `desc` tag. cfg must be a struct or a pointer to a struct, pointer can be nil.
+// Slices and maps of structs have no flags, because their keys aren't known in advance.
+// Values are checked when flags are parsed, with the same conversion ParseConfig uses.
+// Only naming options are used from opts, flags are named without KeyPrefix: --db-host for MYSVC_DB_HOST.
+func RegisterFlags(fs *flag.FlagSet, cfg any, opts ...Option) (*FlagSource, error) {
+	t := reflect.TypeOf(cfg)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errs.New("config must be a struct or a pointer to a struct")
+	}
+
+	source := &FlagSource{
+		names:  make(map[string]string),
+		values: make(map[string]string),
+	}
+	n := newOptions(opts).naming
+	var err error
+	n.walkFields(t, "", "", nil, func(field reflect.StructField, key, _ string) bool {
+		if isStructCollection(field.Type) {
+			return true
+		}
+
+		name := n.flagName(key)
+		if fs.Lookup(name) != nil {
+			err = errs.Newf("flag %s for %s is already defined", name, key)
+			return false
+		}
+
+		source.names[key] = name
+		fs.Var(&flagValue{source: source, key: key, field: field}, name, flagUsage(field, key))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+func (f *FlagSource) Lookup(key string) (string, bool) {
+	value, ok := f.values[key]
+	return value, ok
+}
+
+func (f *FlagSource) Keys() []string {
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (f *FlagSource) Origin(key string) string {
+	return "flag --" + f.names[key]
+}
+
+// flagName converts env key to flag name: DB_HOST to db-host.
+func (n naming) flagName(key string) string {
+	name := strings.ToLower(strings.TrimPrefix(key, n.prefix))
+	name = strings.ReplaceAll(name, n.sep(), "-")
+	return strings.ReplaceAll(name, "_", "-")
+}
+
+func flagUsage(field reflect.StructField, key string) string {
+	usage := field.Tag.Get("desc")
+	if usage == "" {
+		usage = field.Type.String()
+	}
+	if field.Tag.Get("required") == "true" {
+		usage += " (required)"
+	}
+	return usage + " (env " + key + ")"
+}
+
+// flagValue is a flag.Value of a single config key.
+type flagValue struct {
+	source *FlagSource
+	key    string
+	field  reflect.StructField
+}
+
+// String returns default of the field, flag.PrintDefaults prints it.
+// flag package calls String on zero value to find out whether default is set.
+func (v *flagValue) String() string {
+	if v == nil || v.field.Type == nil || isSecret(v.field) {
+		return ""
+	}
+	return v.field.Tag.Get("default")
+}
+
+func (v *flagValue) Set(value string) error {
+	// flags are not expanded unless field has `expand:"true"` tag, then values are checked by ParseConfig
+	if v.field.Tag.Get("expand") != "true" || !strings.Contains(value, "$") {
+		check := reflect.New(v.field.Type).Elem()
+		if err := setFieldValue(check, value, v.field.Tag); err != nil {
+			return err
+		}
+	}
+	v.source.values[v.key] = value
+	return nil
+}
+
+// IsBoolFlag lets boolean flags be set without value: --debug.
+func (v *flagValue) IsBoolFlag() bool {
+	t := v.field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Bool
+}
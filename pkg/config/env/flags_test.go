@@ -0,0 +1,91 @@
+package env
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type flagsConfig struct {
+	Db struct {
+		Host string `env:"HOST" required:"true" desc:"Database host"`
+		Port int    `env:"PORT" default:"5432"`
+	} `env:"DB"`
+	Debug     bool             `env:"DEBUG"`
+	Timeout   time.Duration    `env:"TIMEOUT" default:"10s"`
+	Password  Secret[string]   `env:"PASSWORD" default:"changeme"`
+	Upstreams []upstreamConfig `env:"UPSTREAMS"`
+}
+
+func TestRegisterFlags(t *testing.T) {
+	t.Run("flags override env which overrides defaults", func(t *testing.T) {
+		t.Parallel()
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags, err := RegisterFlags(fs, (*flagsConfig)(nil))
+		require.NoError(t, err)
+		require.NoError(t, fs.Parse([]string{"--db-host", "flag-host", "--debug", "-timeout=1m"}))
+
+		cfg := &flagsConfig{}
+		err = ParseConfig(cfg, FromSources(flags, MapSource{"DB_HOST": "env-host", "DB_PORT": "6432"}))
+		require.NoError(t, err)
+
+		require.Equal(t, "flag-host", cfg.Db.Host)
+		require.Equal(t, 6432, cfg.Db.Port)
+		require.True(t, cfg.Debug)
+		require.Equal(t, time.Minute, cfg.Timeout)
+		require.Equal(t, "changeme", cfg.Password.Value())
+		require.Equal(t, "flag --db-host", flags.Origin("DB_HOST"))
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		t.Parallel()
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(&bytes.Buffer{})
+		_, err := RegisterFlags(fs, flagsConfig{})
+		require.NoError(t, err)
+
+		err = fs.Parse([]string{"--db-port", "abc"})
+		require.Error(t, err)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		t.Parallel()
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		_, err := RegisterFlags(fs, (*flagsConfig)(nil))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		fs.SetOutput(&buf)
+		fs.PrintDefaults()
+		usage := buf.String()
+
+		require.True(t, strings.Contains(usage, "Database host (required) (env DB_HOST)"))
+		require.True(t, strings.Contains(usage, `int (env DB_PORT) (default 5432)`))
+		require.True(t, strings.Contains(usage, "-debug\n"))
+		require.False(t, strings.Contains(usage, "changeme"))
+		require.False(t, strings.Contains(usage, "upstreams"))
+	})
+
+	t.Run("conflicting flag", func(t *testing.T) {
+		t.Parallel()
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("debug", "", "")
+		_, err := RegisterFlags(fs, (*flagsConfig)(nil))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,81 @@
+package env
+
+import "reflect"
+
+// Defaulter is implemented by configs which provide their default values to Load.
+// Unlike `default` tags, defaults are type-checked and can hold any value, e.g. slices of structs.
+//
+//	func (Config) Defaults() Config {
+//		return Config{Upstreams: []Upstream{{Host: "localhost", Port: 8080}}}
+//	}
+type Defaulter[T any] interface {
+	Defaults() T
+}
+
+// Load returns config of type T parsed from process environment or sources set by FromSources.
+// Parsing starts from the value returned by Defaults method of T, if T implements Defaulter,
+// or from the zero value otherwise:
+//
+//	cfg, err := env.Load[Config]()
+func Load[T any](opts ...Option) (T, error) {
+	return LoadWithDefaults(defaultsOf[T](), opts...)
+}
+
+// LoadWithDefaults returns config parsed on top of defaults.
+// Only present keys override defaults, so fields of defaults without keys are kept.
+// `default` tags are applied only to fields which are zero in defaults,
+// non-zero fields of defaults satisfy `required` tags.
+// Slices and maps of structs are replaced as a whole if any of their keys is present.
+func LoadWithDefaults[T any](defaults T, opts ...Option) (T, error) {
+	cfg := defaults
+	cloneNested(reflect.ValueOf(&cfg).Elem())
+	err := ParseConfig(&cfg, append(opts, keepValues())...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return cfg, nil
+}
+
+// keepValues makes parser keep non-zero fields instead of applying `default` tag.
+func keepValues() Option {
+	return func(o *options) {
+		o.keepValues = true
+	}
+}
+
+// defaultsOf returns defaults of T from its Defaults method with value or pointer receiver.
+func defaultsOf[T any]() T {
+	var cfg T
+	if d, ok := any(cfg).(Defaulter[T]); ok {
+		return d.Defaults()
+	}
+	if d, ok := any(&cfg).(Defaulter[T]); ok {
+		return d.Defaults()
+	}
+	return cfg
+}
+
+// cloneNested replaces pointers to nested structs with pointers to their copies,
+// so parser doesn't modify structs shared with defaults.
+func cloneNested(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !v.Type().Field(i).IsExported() || !isNestedStruct(field.Type()) {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			clone := reflect.New(field.Type().Elem())
+			clone.Elem().Set(field.Elem())
+			field.Set(clone)
+			field = clone.Elem()
+		}
+		cloneNested(field)
+	}
+}
@@ -0,0 +1,116 @@
+package env
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type loadConfig struct {
+	Host      string           `env:"HOST" default:"tag-host"`
+	Port      int              `env:"PORT" default:"80"`
+	Timeout   time.Duration    `env:"TIMEOUT"`
+	Upstreams []upstreamConfig `env:"UPSTREAMS"`
+	Cache     *CacheConfig     `env:"CACHE"`
+}
+
+func (loadConfig) Defaults() loadConfig {
+	return loadConfig{
+		Host:      "go-host",
+		Timeout:   5 * time.Second,
+		Upstreams: []upstreamConfig{{Host: "default", Port: 8080}},
+	}
+}
+
+type pointerDefaults struct {
+	Name string `env:"NAME"`
+}
+
+func (c *pointerDefaults) Defaults() pointerDefaults {
+	return pointerDefaults{Name: "pointer"}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("defaults method", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := Load[loadConfig](FromSources(MapSource{"TIMEOUT": "1m"}))
+		require.NoError(t, err)
+
+		require.Equal(t, "go-host", cfg.Host) // Go default wins over tag
+		require.Equal(t, 80, cfg.Port)        // tag applies to zero field
+		require.Equal(t, time.Minute, cfg.Timeout)
+		require.EqualValues(t, []upstreamConfig{{Host: "default", Port: 8080}}, cfg.Upstreams)
+		require.Nil(t, cfg.Cache)
+	})
+
+	t.Run("env overrides defaults", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := Load[loadConfig](FromSources(MapSource{
+			"HOST":             "env-host",
+			"UPSTREAMS_0_HOST": "a",
+			"UPSTREAMS_1_HOST": "b",
+		}))
+		require.NoError(t, err)
+
+		require.Equal(t, "env-host", cfg.Host)
+		require.EqualValues(t, []upstreamConfig{{Host: "a", Port: 80}, {Host: "b", Port: 80}}, cfg.Upstreams)
+	})
+
+	t.Run("pointer receiver", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := Load[pointerDefaults](FromSources(MapSource{}))
+		require.NoError(t, err)
+		require.Equal(t, "pointer", cfg.Name)
+	})
+
+	t.Run("error returns zero value", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := Load[loadConfig](FromSources(MapSource{"PORT": "abc"}))
+		require.Error(t, err)
+		require.Equal(t, "", cfg.Host)
+	})
+}
+
+func TestLoadWithDefaults(t *testing.T) {
+	t.Run("defaults are not modified", func(t *testing.T) {
+		t.Parallel()
+
+		defaults := loadConfig{Cache: &CacheConfig{Addr: "default:6379"}}
+		cfg, err := LoadWithDefaults(defaults, FromSources(MapSource{"CACHE_ADDR": "env:6379"}))
+		require.NoError(t, err)
+
+		require.Equal(t, "env:6379", cfg.Cache.Addr)
+		require.Equal(t, "default:6379", defaults.Cache.Addr)
+		require.Equal(t, "tag-host", cfg.Host)
+	})
+
+	t.Run("required key is still required", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := LoadWithDefaults(testConfig{}, FromSources(MapSource{}))
+		require.ErrorIs(t, err, ErrRequired)
+	})
+
+	t.Run("required key is satisfied by defaults", func(t *testing.T) {
+		t.Parallel()
+
+		type required struct {
+			Host      string           `env:"HOST" required:"true"`
+			Upstreams []upstreamConfig `env:"UPSTREAMS" required:"true"`
+		}
+		defaults := required{Host: "localhost", Upstreams: []upstreamConfig{{Host: "default", Port: 8080}}}
+
+		cfg, err := LoadWithDefaults(defaults, FromSources(MapSource{}))
+		require.NoError(t, err)
+		require.Equal(t, "localhost", cfg.Host)
+		require.Equal(t, 1, len(cfg.Upstreams))
+
+		_, err = LoadWithDefaults(required{Host: "localhost"}, FromSources(MapSource{}))
+		require.ErrorIs(t, err, ErrRequired)
+	})
+}
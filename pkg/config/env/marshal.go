@@ -0,0 +1,298 @@
+package env
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// EnvMarshaler is the inverse of EnvUnmarshaler, it's used by Marshal.
+type EnvMarshaler interface {
+	MarshalEnv() (string, error)
+}
+
+// Marshal returns values of the config by the same keys ParseConfig reads,
+// so ParseConfigFrom(&cfg, env.MapSource(values)) produces equal config.
+// Values are formatted with the first method available, in the same order parser uses:
+//
+//  1. EnvMarshaler implemented by the field type
+//  2. formatter of supported standard library type
+//  3. encoding.TextMarshaler implemented by the field type
+//  4. String method of flag.Value implemented by the field type
+//  5. formatter of the kind: string, ints, uints, floats, bool, slice, map or pointer
+//
+// Nil pointers, slices and maps are omitted, so they stay nil after parsing.
+// $ is escaped as $$ in values which parser expands, see fieldExpands. Secrets are written as is.
+// Only naming options like KeyPrefix are used from opts.
+func Marshal(cfg any, opts ...Option) (map[string]string, error) {
+	entries, err := marshalConfig(cfg, newOptions(opts).naming)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		values[entry.key] = entry.value
+	}
+	return values, nil
+}
+
+// WriteDotEnv writes values returned by Marshal to w in .env format, in order of declaration of the fields.
+func WriteDotEnv(w io.Writer, cfg any, opts ...Option) error {
+	entries, err := marshalConfig(cfg, newOptions(opts).naming)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "%s=%s\n", entry.key, quoteDotEnv(entry.value))
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return errs.Wrap(err, "failed to write .env")
+	}
+	return nil
+}
+
+// quoteDotEnv quotes value if it can't be written to .env file as is.
+// Double quotes are used, so references to other variables are expanded the same way as in unquoted values.
+func quoteDotEnv(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n#'\"\\") {
+		return value
+	}
+	return `"` + dotEnvEscaper.Replace(value) + `"`
+}
+
+// dotEnvEscaper escapes value for double quotes, see parseDotEnv.
+var dotEnvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// envEntry is a marshaled value of the config field.
+type envEntry struct {
+	key   string
+	path  string
+	value string
+}
+
+func marshalConfig(cfg any, n naming) ([]envEntry, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, errs.New("config must be a non-nil pointer to a struct")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errs.New("config must be a struct or a pointer to a struct")
+	}
+
+	// copy config, so methods with pointer receivers can be called on its fields
+	addressable := reflect.New(v.Type()).Elem()
+	addressable.Set(v)
+
+	var entries []envEntry
+	if err := marshalStruct(n, addressable, "", "", &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func marshalStruct(n naming, v reflect.Value, prefix, path string, entries *[]envEntry) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if isIgnored(field) {
+			continue
+		}
+
+		key := n.fieldKey(field, prefix)
+		if isNestedStruct(field.Type) {
+			key = n.nestedKey(field, prefix)
+		}
+		if err := marshalField(n, v.Field(i), field, key, joinPath(path, field.Name), entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalField(n naming, v reflect.Value, field reflect.StructField, key, path string, entries *[]envEntry) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Chan, reflect.Func:
+		if v.IsNil() {
+			return nil
+		}
+	}
+
+	switch t := v.Type(); {
+	case isNestedStruct(t):
+		return marshalStruct(n, reflect.Indirect(v), key, path, entries)
+
+	case isStructSlice(t):
+		for i := 0; i < v.Len(); i++ {
+			elemKey, elemPath := n.join(key, strconv.Itoa(i)), path+"["+strconv.Itoa(i)+"]"
+			if err := marshalElement(n, v.Index(i), elemKey, elemPath, entries); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case isStructMap(t):
+		iter := v.MapRange()
+		var names []string
+		elems := make(map[string]reflect.Value, v.Len())
+		for iter.Next() {
+			name, err := formatValue(iter.Key(), field.Tag)
+			if err != nil {
+				return &FieldError{Field: path, Key: key, Err: errs.Wrap(err, "failed to marshal map key")}
+			}
+			if name == "" {
+				return &FieldError{Field: path, Key: key, Err: errs.New("empty map key can't be marshaled")}
+			}
+			names = append(names, name)
+			elems[name] = iter.Value()
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if err := marshalElement(n, elems[name], n.join(key, name), path+"["+name+"]", entries); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := formatValue(v, field.Tag)
+	if err != nil {
+		return &FieldError{Field: path, Key: key, Err: err}
+	}
+	if fieldExpands(field) {
+		value = strings.ReplaceAll(value, "$", "$$")
+	}
+	*entries = append(*entries, envEntry{key: key, path: path, value: value})
+	return nil
+}
+
+// marshalElement marshals element of a slice or a map of structs.
+func marshalElement(n naming, v reflect.Value, key, path string, entries *[]envEntry) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return &FieldError{Field: path, Key: key, Err: errs.New("nil element can't be marshaled")}
+		}
+		v = v.Elem()
+	}
+	return marshalStruct(n.element(key), addressable(v), key, path, entries)
+}
+
+// addressable returns v or its addressable copy.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// formatValue is the inverse of setFieldValue.
+func formatValue(v reflect.Value, tag reflect.StructTag) (string, error) {
+	v = unwrapSecret(addressable(v))
+
+	if marshaler, ok := v.Addr().Interface().(EnvMarshaler); ok {
+		return marshaler.MarshalEnv()
+	}
+
+	if format, ok := typeFormatters[v.Type()]; ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return "", errs.Newf("nil %s can't be marshaled", v.Type())
+		}
+		return format(v.Interface(), tag), nil
+	}
+
+	if marshaler, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+	if reflect.PointerTo(v.Type()).Implements(valueSetterType) {
+		if stringer, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return stringer.String(), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+
+	case reflect.Slice:
+		sep := tagOrDefault(tag, "sep", defaultSep)
+		parts := make([]string, v.Len())
+		for i := range parts {
+			part, err := formatElement(v.Index(i), tag)
+			if err != nil {
+				return "", errs.Wrapf(err, "failed to marshal element %d", i)
+			}
+			parts[i] = escape(part, sep)
+		}
+		if len(parts) == 1 && parts[0] == "" {
+			return "", errs.New("slice with single empty element can't be marshaled")
+		}
+		return strings.Join(parts, sep), nil
+
+	case reflect.Map:
+		sep := tagOrDefault(tag, "sep", defaultSep)
+		kvSep := tagOrDefault(tag, "kvsep", defaultKVSep)
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := formatElement(iter.Key(), tag)
+			if err != nil {
+				return "", errs.Wrap(err, "failed to marshal key")
+			}
+			value, err := formatElement(iter.Value(), tag)
+			if err != nil {
+				return "", errs.Wrapf(err, "failed to marshal value of key %q", key)
+			}
+			entries = append(entries, escape(key, sep, kvSep)+kvSep+escape(value, sep, kvSep))
+		}
+		slices.Sort(entries)
+		return strings.Join(entries, sep), nil
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", errs.Newf("nil %s can't be marshaled", v.Type())
+		}
+		return formatValue(v.Elem(), tag)
+
+	default:
+		return "", errs.Newf("%w %s", ErrUnsupportedType, v.Type())
+	}
+}
+
+// formatElement formats element of a slice or a map, parser trims spaces around them.
+func formatElement(v reflect.Value, tag reflect.StructTag) (string, error) {
+	value, err := formatValue(v, tag)
+	if err != nil {
+		return "", err
+	}
+	if value != strings.TrimSpace(value) {
+		return "", errs.Newf("%q has leading or trailing spaces, which are trimmed by parser", value)
+	}
+	return value, nil
+}
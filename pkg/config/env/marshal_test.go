@@ -0,0 +1,245 @@
+package env
+
+import (
+	"bytes"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type marshaledConfig struct {
+	Name     string            `env:"NAME" default:"default"`
+	Quoted   string            `env:"QUOTED"`
+	Literal  string            `env:"LITERAL" expand:"false"`
+	Count    int               `env:"COUNT" default:"10"`
+	Small    int8              `env:"SMALL"`
+	Size     uint64            `env:"SIZE"`
+	Ratio    float64           `env:"RATIO"`
+	Ratio32  float32           `env:"RATIO32"`
+	Enabled  bool              `env:"ENABLED" default:"true"`
+	Timeout  time.Duration     `env:"TIMEOUT"`
+	Start    time.Time         `env:"START"`
+	Day      time.Time         `env:"DAY" layout:"2006-01-02"`
+	Endpoint *url.URL          `env:"ENDPOINT"`
+	Site     url.URL           `env:"SITE"`
+	IP       net.IP            `env:"IP"`
+	Addr     netip.Addr        `env:"ADDR"`
+	AddrPort netip.AddrPort    `env:"ADDR_PORT"`
+	Prefix   netip.Prefix      `env:"PREFIX"`
+	Pattern  *regexp.Regexp    `env:"PATTERN"`
+	Big      *big.Int          `env:"BIG"`
+	Memory   ByteSize          `env:"MEMORY"`
+	Level    slog.Level        `env:"LEVEL"`
+	Flag     upperFlag         `env:"FLAG"`
+	Hosts    []string          `env:"HOSTS"`
+	Ports    []int             `env:"PORTS" sep:";"`
+	Empty    []string          `env:"EMPTY"`
+	Labels   map[string]string `env:"LABELS"`
+	Weights  map[string]int    `env:"WEIGHTS" sep:";" kvsep:"="`
+	Optional *int              `env:"OPTIONAL"`
+	Password Secret[string]    `env:"PASSWORD"`
+	Db       DbConfig          `env:"DB"`
+	Cache    *CacheConfig      `env:"CACHE"`
+	Common   CommonConfig      `inline:"true"`
+
+	Upstreams []upstreamConfig        `env:"UPSTREAMS"`
+	Tenants   map[string]tenantConfig `env:"TENANTS"`
+}
+
+func newMarshaledConfig() marshaledConfig {
+	return marshaledConfig{
+		Name:     "",
+		Quoted:   "it's \"quoted\" # not a comment\n\tC:\\dir\r\n",
+		Literal:  "$HOME",
+		Count:    0,
+		Small:    -8,
+		Size:     1 << 40,
+		Ratio:    0.1,
+		Ratio32:  0.3,
+		Enabled:  false,
+		Timeout:  1500 * time.Millisecond,
+		Start:    time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC),
+		Day:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Endpoint: &url.URL{Scheme: "https", Host: "example.com", Path: "/api", RawQuery: "a=1"},
+		Site:     url.URL{Scheme: "http", Host: "localhost:8080"},
+		IP:       net.ParseIP("10.0.0.1"),
+		Addr:     netip.MustParseAddr("::1"),
+		AddrPort: netip.MustParseAddrPort("127.0.0.1:80"),
+		Prefix:   netip.MustParsePrefix("10.0.0.0/8"),
+		Pattern:  regexp.MustCompile(`^a,b\d+$`),
+		Big:      new(big.Int).Lsh(big.NewInt(1), 100),
+		Memory:   64 * MiB,
+		Level:    slog.LevelWarn,
+		Flag:     "UPPER",
+		Hosts:    []string{"a,b", `c\d`, "e$f"},
+		Ports:    []int{80, 443},
+		Empty:    []string{},
+		Labels:   map[string]string{"env": "prod", "url": "http://x"},
+		Weights:  map[string]int{"a": 1, "b": 2},
+		Password: NewSecret("p@ss word"),
+		Db:       DbConfig{Host: "db", Port: 6432},
+		Cache:    &CacheConfig{Addr: "cache:6379"},
+		Common:   CommonConfig{},
+		Upstreams: []upstreamConfig{
+			{Host: "a", Port: 80},
+			{Host: "b", Port: 8080, Weight: 2},
+		},
+		Tenants: map[string]tenantConfig{
+			"acme": {Host: "acme.local", Password: NewSecret("secret")},
+		},
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Run("values", func(t *testing.T) {
+		t.Parallel()
+
+		values, err := Marshal(newMarshaledConfig())
+		require.NoError(t, err)
+
+		require.Equal(t, "", values["NAME"])
+		require.Equal(t, "$HOME", values["LITERAL"])
+		require.Equal(t, "1.5s", values["TIMEOUT"])
+		require.Equal(t, "2024-05-01", values["DAY"])
+		require.Equal(t, "64MiB", values["MEMORY"])
+		require.Equal(t, "WARN", values["LEVEL"])
+		require.Equal(t, `a\,b,c\\d,e$$f`, values["HOSTS"])
+		require.Equal(t, "80;443", values["PORTS"])
+		require.Equal(t, "", values["EMPTY"])
+		require.Equal(t, `env:prod,url:http\://x`, values["LABELS"])
+		require.Equal(t, "a=1;b=2", values["WEIGHTS"])
+		require.Equal(t, "p@ss word", values["PASSWORD"])
+		require.Equal(t, "cache:6379", values["CACHE_ADDR"])
+		require.Equal(t, "b", values["UPSTREAMS_1_HOST"])
+		require.Equal(t, "acme.local", values["TENANTS_acme_HOST"])
+
+		_, ok := values["OPTIONAL"]
+		require.False(t, ok)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		expected := newMarshaledConfig()
+		values, err := Marshal(&expected)
+		require.NoError(t, err)
+
+		var actual marshaledConfig
+		require.NoError(t, ParseConfigFrom(&actual, MapSource(values)))
+
+		assertMarshaledConfig(t, expected, actual)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Marshal(nil)
+		require.Error(t, err)
+
+		_, err = Marshal((*marshaledConfig)(nil))
+		require.Error(t, err)
+	})
+
+	t.Run("values that can't be parsed back", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Marshal(struct {
+			Hosts []string `env:"HOSTS"`
+		}{Hosts: []string{" a"}})
+		require.Error(t, err)
+
+		_, err = Marshal(struct {
+			Hosts []string `env:"HOSTS"`
+		}{Hosts: []string{""}})
+		require.Error(t, err)
+
+		_, err = Marshal(struct {
+			Upstreams []*upstreamConfig `env:"UPSTREAMS"`
+		}{Upstreams: []*upstreamConfig{nil}})
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "UPSTREAMS_0", fieldErr.Key)
+
+		_, err = Marshal(struct {
+			CH chan int `env:"CH"`
+		}{CH: make(chan int)})
+		require.ErrorIs(t, err, ErrUnsupportedType)
+	})
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		t.Parallel()
+
+		expected := newMarshaledConfig()
+		var buf bytes.Buffer
+		require.NoError(t, WriteDotEnv(&buf, expected))
+
+		path := filepath.Join(t.TempDir(), ".env")
+		require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+		source, err := DotEnvSource(path)
+		require.NoError(t, err)
+
+		var actual marshaledConfig
+		require.NoError(t, ParseConfigFrom(&actual, source))
+
+		assertMarshaledConfig(t, expected, actual)
+	})
+
+	t.Run("quoting", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := WriteDotEnv(&buf, struct {
+			Plain  string `env:"PLAIN"`
+			Spaces string `env:"SPACES"`
+			Hash   string `env:"HASH"`
+		}{Plain: "a=b", Spaces: " a ", Hash: "a#b"})
+		require.NoError(t, err)
+
+		require.Equal(t, "PLAIN=a=b\nSPACES=\" a \"\nHASH=\"a#b\"\n", buf.String())
+	})
+
+	t.Run("multiline value", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		err := WriteDotEnv(&buf, struct {
+			Cert string `env:"CERT"`
+		}{Cert: "line1\nline2"})
+		require.NoError(t, err)
+		require.Equal(t, "CERT=\"line1\\nline2\"\n", buf.String())
+	})
+}
+
+// assertMarshaledConfig compares configs field by field, because some types can't be compared with ==.
+func assertMarshaledConfig(t *testing.T, expected, actual marshaledConfig) {
+	t.Helper()
+
+	require.Equal(t, expected.Password.Value(), actual.Password.Value())
+	require.Equal(t, expected.Pattern.String(), actual.Pattern.String())
+	require.Equal(t, 0, expected.Big.Cmp(actual.Big))
+	require.True(t, expected.Start.Equal(actual.Start))
+	require.True(t, expected.Day.Equal(actual.Day))
+	require.True(t, expected.IP.Equal(actual.IP))
+	require.Equal(t, expected.Tenants["acme"].Password.Value(), actual.Tenants["acme"].Password.Value())
+
+	expected.Password, actual.Password = Secret[string]{}, Secret[string]{}
+	expected.Pattern, actual.Pattern = nil, nil
+	expected.Big, actual.Big = nil, nil
+	expected.Start, actual.Start = time.Time{}, time.Time{}
+	expected.Day, actual.Day = time.Time{}, time.Time{}
+	expected.IP, actual.IP = nil, nil
+	expected.Tenants, actual.Tenants = nil, nil
+	require.EqualValues(t, expected, actual)
+}
@@ -0,0 +1,134 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Keys are built from `env` tags or names of the fields, nested keys are joined with "_":
+//
+//	type Config struct {
+//		MaxConns int      `env:"MAX_CONNS"` // MAX_CONNS
+//		Db       DbConfig                   // DB_HOST, field name is uppercased
+//		Pg       PgConfig `prefix:"PG"`     // PG_HOST, prefix tag ignores prefixes of the parents
+//		Cache    *Cache   `env:"-"`         // ignored by parser, Describe, Marshal and RegisterFlags
+//	}
+//
+// KeyNaming, KeySeparator and KeyPrefix options change how keys are built:
+//
+//	env.ParseConfig(&cfg, env.KeyPrefix("MYSVC_"), env.KeyNaming(env.ScreamingSnake)) // MYSVC_MAX_CONNS
+
+// KeyPrefix sets prefix of all keys, it's prepended as is: KeyPrefix("MYSVC_") reads MYSVC_DB_HOST.
+func KeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.naming.prefix = prefix
+	}
+}
+
+// KeySeparator sets separator of nested keys and indexes of collections, "_" by default.
+func KeySeparator(sep string) Option {
+	return func(o *options) {
+		o.naming.separator = sep
+	}
+}
+
+// KeyNaming sets conversion of field names without `env` tag to keys, strings.ToUpper by default.
+// Values of `env` and `prefix` tags are uppercased regardless of the naming.
+func KeyNaming(name func(fieldName string) string) Option {
+	return func(o *options) {
+		o.naming.name = name
+	}
+}
+
+// ScreamingSnake converts CamelCase field name to SCREAMING_SNAKE_CASE key:
+// MaxConns to MAX_CONNS, HTTPServer to HTTP_SERVER, Version2Name to VERSION2_NAME.
+func ScreamingSnake(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// naming builds keys of the fields, zero value builds keys the default way.
+type naming struct {
+	prefix    string              // prepended to keys of the root struct and `prefix` tags
+	separator string              // joins nested keys, "_" if empty
+	name      func(string) string // converts field name to key, strings.ToUpper if nil
+	base      string              // key of the collection element `prefix` tags are relative to, empty for root struct
+}
+
+func (n naming) sep() string {
+	if n.separator == "" {
+		return "_"
+	}
+	return n.separator
+}
+
+// join joins name to the key of the parent, empty prefix means root struct.
+func (n naming) join(prefix, name string) string {
+	if prefix == "" {
+		return n.prefix + name
+	}
+	return prefix + n.sep() + name
+}
+
+// fieldKey returns key of the field: uppercased env tag or converted field name, joined to prefix.
+func (n naming) fieldKey(field reflect.StructField, prefix string) string {
+	name := strings.ToUpper(field.Tag.Get("env"))
+	if name == "" {
+		name = n.convert(field.Name)
+	}
+	return n.join(prefix, name)
+}
+
+// convert converts field name to key with the naming function.
+func (n naming) convert(name string) string {
+	if n.name == nil {
+		return strings.ToUpper(name)
+	}
+	return n.name(name)
+}
+
+// nestedKey returns prefix of the keys of nested struct field.
+// Inline structs share prefix of the parent. `prefix` tag is joined to the global prefix
+// or to the key of collection element the field belongs to: UPS_0_PG for []Up with `prefix:"PG"` inside.
+func (n naming) nestedKey(field reflect.StructField, prefix string) string {
+	if field.Tag.Get("inline") == "true" {
+		return prefix
+	}
+	if tag, ok := field.Tag.Lookup("prefix"); ok {
+		if tag == "" {
+			return n.base
+		}
+		return n.join(n.base, strings.ToUpper(tag))
+	}
+	return n.fieldKey(field, prefix)
+}
+
+// relative returns naming of the keys relative to another key, without the global prefix.
+func (n naming) relative() naming {
+	n.prefix = ""
+	n.base = ""
+	return n
+}
+
+// element returns naming of the keys of collection element with the key.
+func (n naming) element(key string) naming {
+	n.base = key
+	return n
+}
+
+// isIgnored reports whether field isn't read from env: it's unexported or has `env:"-"` tag.
+func isIgnored(field reflect.StructField) bool {
+	return !field.IsExported() || field.Tag.Get("env") == "-"
+}
@@ -0,0 +1,225 @@
+package env
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type namedConfig struct {
+	MaxConns   int `default:"10"`
+	HTTPServer struct {
+		ReadTimeout string
+	}
+	Pg struct {
+		Host string `env:"HOST"`
+	} `prefix:"PG"`
+	Nodes    []struct{ Addr string }
+	Internal string `env:"-"`
+}
+
+func TestScreamingSnake(t *testing.T) {
+	tests := map[string]string{
+		"MaxConns":     "MAX_CONNS",
+		"HTTPServer":   "HTTP_SERVER",
+		"DBHost":       "DB_HOST",
+		"Version2Name": "VERSION2_NAME",
+		"ID":           "ID",
+		"Port":         "PORT",
+		"Already_Set":  "ALREADY_SET",
+	}
+	for name, want := range tests {
+		require.Equal(t, want, ScreamingSnake(name))
+	}
+}
+
+func TestParseConfig_Naming(t *testing.T) {
+	t.Run("default naming uppercases field names", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &namedConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"MAXCONNS":               "20",
+			"HTTPSERVER_READTIMEOUT": "5s",
+			"PG_HOST":                "localhost",
+			"NODES_0_ADDR":           "a:1",
+			"INTERNAL":               "set",
+			"-":                      "set",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, 20, cfg.MaxConns)
+		require.Equal(t, "5s", cfg.HTTPServer.ReadTimeout)
+		require.Equal(t, "localhost", cfg.Pg.Host)
+		require.Equal(t, 1, len(cfg.Nodes))
+		require.Equal(t, "a:1", cfg.Nodes[0].Addr)
+		require.Equal(t, "", cfg.Internal)
+	})
+
+	t.Run("prefix, separator and screaming snake", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &namedConfig{}
+		err := ParseConfig(cfg,
+			FromSources(MapSource{
+				"MYSVC_MAX_CONNS":                 "20",
+				"MYSVC_HTTP_SERVER__READ_TIMEOUT": "5s",
+				"MYSVC_PG__HOST":                  "localhost",
+				"MYSVC_NODES__0__ADDR":            "a:1",
+				"MYSVC_NODES__1__ADDR":            "b:2",
+			}),
+			KeyPrefix("MYSVC_"),
+			KeySeparator("__"),
+			KeyNaming(ScreamingSnake),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, 20, cfg.MaxConns)
+		require.Equal(t, "5s", cfg.HTTPServer.ReadTimeout)
+		require.Equal(t, "localhost", cfg.Pg.Host)
+		require.Equal(t, 2, len(cfg.Nodes))
+		require.Equal(t, "b:2", cfg.Nodes[1].Addr)
+	})
+
+	t.Run("prefix tag ignores prefixes of the parents", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg struct {
+			Storage struct {
+				Pg struct {
+					Host string `env:"HOST"`
+				} `prefix:"PG"`
+				Root struct {
+					Debug bool `env:"DEBUG"`
+				} `prefix:""`
+			} `env:"STORAGE"`
+		}
+		err := ParseConfig(&cfg,
+			FromSources(MapSource{"APP_PG_HOST": "localhost", "APP_DEBUG": "true"}),
+			KeyPrefix("APP_"),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "localhost", cfg.Storage.Pg.Host)
+		require.True(t, cfg.Storage.Root.Debug)
+	})
+
+	t.Run("prefix tag inside collection element keeps element key", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg struct {
+			Ups []struct {
+				Pg struct {
+					Host string `env:"HOST"`
+				} `prefix:"PG"`
+			} `env:"UPS"`
+			Tenants map[string]struct {
+				Pg struct {
+					Host string `env:"HOST"`
+				} `prefix:"PG"`
+			} `env:"TENANTS"`
+		}
+		err := ParseConfigFrom(&cfg, MapSource{
+			"PG_HOST":              "root",
+			"UPS_0_PG_HOST":        "a",
+			"UPS_1_PG_HOST":        "b",
+			"TENANTS_ACME_PG_HOST": "acme",
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(cfg.Ups))
+		require.Equal(t, "b", cfg.Ups[1].Pg.Host)
+		require.Equal(t, 1, len(cfg.Tenants))
+		require.Equal(t, "acme", cfg.Tenants["ACME"].Pg.Host)
+
+		values, err := Marshal(cfg)
+		require.NoError(t, err)
+		require.EqualValues(t, map[string]string{
+			"UPS_0_PG_HOST":        "a",
+			"UPS_1_PG_HOST":        "b",
+			"TENANTS_ACME_PG_HOST": "acme",
+		}, values)
+
+		vars, err := Describe(cfg)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(vars))
+		require.Equal(t, "UPS_<N>_PG_HOST", vars[0].Key)
+		require.Equal(t, "TENANTS_<NAME>_PG_HOST", vars[1].Key)
+	})
+
+	t.Run("map names with separator", func(t *testing.T) {
+		t.Parallel()
+
+		var cfg struct {
+			Tenants map[string]struct {
+				DbHost string
+			}
+		}
+		err := ParseConfig(&cfg,
+			FromSources(MapSource{"SVC.TENANTS.ACME_CORP.DB_HOST": "acme"}),
+			KeyPrefix("SVC."),
+			KeySeparator("."),
+			KeyNaming(ScreamingSnake),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "acme", cfg.Tenants["ACME_CORP"].DbHost)
+	})
+
+	t.Run("errors name prefixed keys", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &testConfig{}
+		err := ParseConfig(cfg, FromSources(MapSource{}), KeyPrefix("MYSVC_"))
+		require.ErrorIs(t, err, ErrRequired)
+		require.True(t, strings.Contains(err.Error(), "MYSVC_DB_HOST"))
+	})
+}
+
+func TestNaming_Consumers(t *testing.T) {
+	opts := []Option{KeyPrefix("MYSVC_"), KeyNaming(ScreamingSnake)}
+
+	t.Run("describe", func(t *testing.T) {
+		vars, err := Describe((*namedConfig)(nil), opts...)
+		require.NoError(t, err)
+
+		keys := make([]string, 0, len(vars))
+		for _, v := range vars {
+			keys = append(keys, v.Key)
+		}
+		require.EqualValues(t, []string{
+			"MYSVC_MAX_CONNS", "MYSVC_HTTP_SERVER_READ_TIMEOUT", "MYSVC_PG_HOST", "MYSVC_NODES_<N>_ADDR",
+		}, keys)
+	})
+
+	t.Run("marshal round trip", func(t *testing.T) {
+		cfg := namedConfig{MaxConns: 5, Internal: "skipped"}
+		cfg.Pg.Host = "localhost"
+		cfg.Nodes = []struct{ Addr string }{{Addr: "a:1"}}
+
+		values, err := Marshal(cfg, opts...)
+		require.NoError(t, err)
+		require.EqualValues(t, map[string]string{
+			"MYSVC_MAX_CONNS":                "5",
+			"MYSVC_HTTP_SERVER_READ_TIMEOUT": "",
+			"MYSVC_PG_HOST":                  "localhost",
+			"MYSVC_NODES_0_ADDR":             "a:1",
+		}, values)
+
+		parsed := namedConfig{}
+		require.NoError(t, ParseConfig(&parsed, append(opts, FromSources(MapSource(values)))...))
+		require.Equal(t, "localhost", parsed.Pg.Host)
+		require.Equal(t, 5, parsed.MaxConns)
+	})
+
+	t.Run("flags are named without prefix", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		source, err := RegisterFlags(fs, (*namedConfig)(nil), opts...)
+		require.NoError(t, err)
+
+		require.NoError(t, fs.Parse([]string{"--max-conns=7", "--pg-host=db"}))
+		value, ok := source.Lookup("MYSVC_MAX_CONNS")
+		require.True(t, ok)
+		require.Equal(t, "7", value)
+		require.True(t, fs.Lookup("internal") == nil)
+	})
+}
@@ -0,0 +1,112 @@
+package env
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pechorka/gostdlib/pkg/httpx"
+)
+
+const (
+	originDefault = "default"
+	originUnset   = "unset"
+)
+
+// Provenance tells where values of the config fields come from.
+// It's filled by ParseConfig with WithProvenance option:
+//
+//	var report env.Provenance
+//	err := env.ParseConfig(&cfg, env.WithProvenance(&report))
+//	mux.Handle("/debug/config", report)
+type Provenance []Origin
+
+// Origin describes where value of a single field comes from.
+type Origin struct {
+	Field string `json:"field"` // Go path of the field, e.g. Db.Host
+	Key   string `json:"key"`   // env key of the field, e.g. DB_HOST
+	// Source of the value:
+	//	env                  - process environment
+	//	.env:12              - file and line
+	//	default              - `default` tag
+	//	file /run/secrets/db - secret read from file set by KEY_FILE
+	//	unset                - key is not set and field is left untouched
+	// Sources which don't implement Originer are named by their type.
+	Source string `json:"source"`
+	Value  string `json:"value"` // raw value before expansion and parsing, redacted for secrets
+}
+
+// WithProvenance makes ParseConfig fill report with origins of the parsed fields.
+// Report is filled even if parsing fails, to help find the wrong value.
+func WithProvenance(report *Provenance) Option {
+	return func(o *options) {
+		o.provenance = report
+	}
+}
+
+// recordOrigin adds origin of the field value returned by getEnvValue to the report.
+// Value is recorded as written in the source or `default` tag, before expansion,
+// so values referencing secrets like postgres://${DB_PASSWORD}@host don't expose them.
+func (p *parser) recordOrigin(field reflect.StructField, key, path, value string, ok bool) {
+	sourceValue, inSource := p.source.Lookup(key)
+
+	filePath, inFile := "", false
+	if isSecret(field) {
+		filePath, inFile = p.source.Lookup(key + fileSuffix)
+	}
+	notEmpty := field.Tag.Get("notEmpty") == "true"
+
+	origin := Origin{Field: path, Key: key, Value: value}
+	switch {
+	case !ok:
+		origin.Source = originUnset
+	case inSource && !(notEmpty && sourceValue == ""):
+		origin.Source = sourceOrigin(p.source, key)
+		origin.Value = sourceValue
+	case inFile:
+		origin.Source = "file " + filePath
+	default:
+		origin.Source = originDefault
+		origin.Value = field.Tag.Get("default")
+	}
+	if isSecret(field) && value != "" || strings.HasPrefix(sourceValue, encryptedPrefix) {
+		origin.Value = redacted
+	}
+	*p.provenance = append(*p.provenance, origin)
+}
+
+// Lookup returns origin of the field by its Go path.
+func (p Provenance) Lookup(field string) (Origin, bool) {
+	for _, origin := range p {
+		if origin.Field == field {
+			return origin, true
+		}
+	}
+	return Origin{}, false
+}
+
+// String renders report as a table.
+func (p Provenance) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tKEY\tSOURCE\tVALUE")
+	for _, origin := range p {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%q\n", origin.Field, origin.Key, origin.Source, origin.Value)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+// ServeHTTP writes report as JSON, or as a table if request has ?format=text.
+func (p Provenance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, p.String())
+		return
+	}
+	if err := httpx.WriteJSON(w, p); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
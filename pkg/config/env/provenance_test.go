@@ -0,0 +1,111 @@
+package env
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type provenanceConfig struct {
+	Host     string         `env:"PROV_HOST"`
+	Port     int            `env:"PROV_PORT" default:"5432"`
+	Name     string         `env:"PROV_NAME" default:"app" notEmpty:"true"`
+	Login    string         `env:"PROV_LOGIN"`
+	Password Secret[string] `env:"PROV_PASSWORD"`
+	Token    string         `env:"PROV_TOKEN" secret:"true"`
+	Timeout  string         `env:"PROV_TIMEOUT"`
+	Missing  string         `env:"PROV_MISSING"`
+}
+
+func TestWithProvenance(t *testing.T) {
+	dir := t.TempDir()
+	dotEnv := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(dotEnv, []byte("# comment\nPROV_LOGIN=admin\nPROV_TOKEN=abc\n"), 0o600))
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("secret"), 0o600))
+
+	fileSource, err := DotEnvSource(dotEnv)
+	require.NoError(t, err)
+
+	t.Run("origins", func(t *testing.T) {
+		prepareEnv(t, "PROV_HOST", "localhost", "PROV_NAME", "")
+
+		var report Provenance
+		cfg := &provenanceConfig{}
+		err := ParseConfig(cfg, WithProvenance(&report), FromSources(
+			OSSource{},
+			MapSource{"PROV_PASSWORD_FILE": passwordFile, "PROV_TIMEOUT": "10s"},
+			fileSource,
+		))
+		require.NoError(t, err)
+
+		require.EqualValues(t, Provenance{
+			{Field: "Host", Key: "PROV_HOST", Source: "env", Value: "localhost"},
+			{Field: "Port", Key: "PROV_PORT", Source: "default", Value: "5432"},
+			{Field: "Name", Key: "PROV_NAME", Source: "default", Value: "app"},
+			{Field: "Login", Key: "PROV_LOGIN", Source: dotEnv + ":2", Value: "admin"},
+			{Field: "Password", Key: "PROV_PASSWORD", Source: "file " + passwordFile, Value: redacted},
+			{Field: "Token", Key: "PROV_TOKEN", Source: dotEnv + ":3", Value: redacted},
+			{Field: "Timeout", Key: "PROV_TIMEOUT", Source: "map", Value: "10s"},
+			{Field: "Missing", Key: "PROV_MISSING", Source: "unset"},
+		}, report)
+
+		origin, ok := report.Lookup("Port")
+		require.True(t, ok)
+		require.Equal(t, "default", origin.Source)
+	})
+
+	t.Run("filled on error", func(t *testing.T) {
+		var report Provenance
+		cfg := &provenanceConfig{}
+		err := ParseConfig(cfg, WithProvenance(&report), FromSources(MapSource{"PROV_PORT": "abc"}))
+		require.Error(t, err)
+
+		origin, ok := report.Lookup("Port")
+		require.True(t, ok)
+		require.Equal(t, "abc", origin.Value)
+	})
+
+	t.Run("values are recorded before expansion", func(t *testing.T) {
+		var report Provenance
+		cfg := &struct {
+			Password string `env:"PROV_PASSWORD" secret:"true"`
+			URL      string `env:"PROV_URL"`
+			Backup   string `env:"PROV_BACKUP" default:"${PROV_PASSWORD}"`
+		}{}
+		err := ParseConfig(cfg, WithProvenance(&report), FromSources(MapSource{
+			"PROV_PASSWORD": "s3cr3t",
+			"PROV_URL":      "postgres://u:${PROV_PASSWORD}@h",
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "postgres://u:s3cr3t@h", cfg.URL)
+
+		origin, ok := report.Lookup("URL")
+		require.True(t, ok)
+		require.Equal(t, "postgres://u:${PROV_PASSWORD}@h", origin.Value)
+
+		origin, ok = report.Lookup("Backup")
+		require.True(t, ok)
+		require.Equal(t, "${PROV_PASSWORD}", origin.Value)
+		require.False(t, strings.Contains(report.String(), "s3cr3t"))
+	})
+
+	t.Run("http", func(t *testing.T) {
+		report := Provenance{{Field: "Host", Key: "HOST", Source: "env", Value: "localhost"}}
+
+		rec := httptest.NewRecorder()
+		report.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Equal(t, `[{"field":"Host","key":"HOST","source":"env","value":"localhost"}]`, strings.TrimSpace(rec.Body.String()))
+
+		rec = httptest.NewRecorder()
+		report.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/config?format=text", nil))
+		require.Equal(t, report.String(), rec.Body.String())
+		require.True(t, strings.Contains(rec.Body.String(), `Host   HOST  env     "localhost"`))
+	})
+}
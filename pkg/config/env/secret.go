@@ -0,0 +1,102 @@
+package env
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// redacted replaces values of secrets in output
+const redacted = "******"
+
+// fileSuffix is appended to the key to read value from file, e.g. DB_PASSWORD_FILE=/run/secrets/db
+const fileSuffix = "_FILE"
+
+// Secret holds a config value that must not be printed.
+// String, GoString and MarshalJSON return redacted value, use Value to get the real one.
+// Unlike `secret:"true"` tag, Secret also hides the value when the whole config is printed or encoded.
+// Secret[T] is parsed the same way as T, including all tags:
+//
+//	type Config struct {
+//		Password env.Secret[string] `env:"PASSWORD" required:"true"`
+//	}
+type Secret[T any] struct {
+	value T
+}
+
+// NewSecret wraps value into Secret.
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the wrapped value.
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+func (s Secret[T]) String() string {
+	return redacted
+}
+
+func (s Secret[T]) GoString() string {
+	return redacted
+}
+
+func (s Secret[T]) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redacted + `"`), nil
+}
+
+func (s *Secret[T]) secretValue() reflect.Value {
+	return reflect.ValueOf(&s.value).Elem()
+}
+
+// secretHolder lets parser fill the value wrapped in Secret with tags of the field.
+type secretHolder interface {
+	secretValue() reflect.Value
+}
+
+var secretHolderType = reflect.TypeFor[secretHolder]()
+
+// isSecret reports whether field has `secret:"true"` tag or Secret type.
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" || reflect.PointerTo(field.Type).Implements(secretHolderType)
+}
+
+// unwrapSecret returns value wrapped in Secret or v itself if it's not a Secret.
+func unwrapSecret(v reflect.Value) reflect.Value {
+	if secret, ok := v.Addr().Interface().(secretHolder); ok {
+		return secret.secretValue()
+	}
+	return v
+}
+
+// readValueFile reads value of KEY_FILE variable.
+// Trailing newline is trimmed, because editors and `echo` append it to secret files.
+func readValueFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", errs.Wrap(err, "failed to read value file")
+	}
+	value := strings.TrimSuffix(string(content), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
+
+// redactedError hides secret value in the message of the wrapped error.
+type redactedError struct {
+	err    error
+	secret string
+}
+
+func (e *redactedError) Error() string {
+	message := strings.ReplaceAll(e.err.Error(), e.secret, redacted)
+	// secret can be quoted with %q, which escapes some of its characters
+	quoted := strconv.Quote(e.secret)
+	return strings.ReplaceAll(message, quoted[1:len(quoted)-1], redacted)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
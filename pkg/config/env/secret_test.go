@@ -0,0 +1,146 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type secretConfig struct {
+	User     string           `env:"USER"`
+	Password Secret[string]   `env:"PASSWORD"`
+	Token    string           `env:"TOKEN" secret:"true"`
+	Pin      Secret[int]      `env:"PIN" min:"1000"`
+	Keys     Secret[[]string] `env:"KEYS" sep:";"`
+}
+
+func TestSecret(t *testing.T) {
+	cfg := secretConfig{User: "admin", Password: NewSecret("qwerty")}
+
+	t.Run("value", func(t *testing.T) {
+		require.Equal(t, "qwerty", cfg.Password.Value())
+	})
+
+	t.Run("printing is redacted", func(t *testing.T) {
+		require.NotContains(t, fmt.Sprintf("%v", cfg), "qwerty")
+		require.NotContains(t, fmt.Sprintf("%+v", cfg), "qwerty")
+		require.NotContains(t, fmt.Sprintf("%#v", cfg.Password), "qwerty")
+		require.Equal(t, "******", cfg.Password.String())
+	})
+
+	t.Run("json is redacted", func(t *testing.T) {
+		data, err := json.Marshal(cfg)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "qwerty")
+		require.Contains(t, string(data), `"Password":"******"`)
+	})
+}
+
+func TestParseConfig_Secrets(t *testing.T) {
+	t.Run("secrets are parsed with tags", func(t *testing.T) {
+		cfg := &secretConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PASSWORD": "qwerty",
+			"TOKEN":    "abc",
+			"PIN":      "1234",
+			"KEYS":     "a;b",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "qwerty", cfg.Password.Value())
+		require.Equal(t, "abc", cfg.Token)
+		require.Equal(t, 1234, cfg.Pin.Value())
+		require.EqualValues(t, []string{"a", "b"}, cfg.Keys.Value())
+	})
+
+	t.Run("secret values are redacted in errors", func(t *testing.T) {
+		cfg := &secretConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"TOKEN": "abc",
+			"PIN":   "12x4",
+		})
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), "12x4")
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "******", fieldErr.Value)
+
+		err = ParseConfigFrom(cfg, MapSource{"PIN": "999"})
+		require.ErrorIs(t, err, ErrInvalid)
+		require.NotContains(t, err.Error(), "999")
+	})
+
+	t.Run("secret value is read from file", func(t *testing.T) {
+		dir := t.TempDir()
+		passwordFile := filepath.Join(dir, "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0o600))
+		tokenFile := filepath.Join(dir, "token")
+		require.NoError(t, os.WriteFile(tokenFile, []byte("token\r\n"), 0o600))
+
+		cfg := &secretConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PASSWORD_FILE": passwordFile,
+			"TOKEN_FILE":    tokenFile,
+			"USER_FILE":     passwordFile,
+			"PIN":           "1234",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "from-file", cfg.Password.Value())
+		require.Equal(t, "token", cfg.Token)
+		require.Equal(t, "", cfg.User) // not a secret
+	})
+
+	t.Run("file satisfies required", func(t *testing.T) {
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("from-file"), 0o600))
+
+		cfg := &struct {
+			Db *struct {
+				Password Secret[string] `env:"PASSWORD" required:"true"`
+			} `env:"DB"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"DB_PASSWORD_FILE": passwordFile})
+		require.NoError(t, err)
+		require.Equal(t, "from-file", cfg.Db.Password.Value())
+	})
+
+	t.Run("both value and file are set", func(t *testing.T) {
+		cfg := &secretConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PASSWORD":      "qwerty",
+			"PASSWORD_FILE": "/run/secrets/password",
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "both PASSWORD and PASSWORD_FILE are set")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		cfg := &secretConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PASSWORD_FILE": filepath.Join(t.TempDir(), "missing"),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestDescribe_Secrets(t *testing.T) {
+	vars, err := Describe(struct {
+		Password Secret[string] `env:"PASSWORD" default:"qwerty"`
+		Token    string         `env:"TOKEN" secret:"true"`
+		User     string         `env:"USER" default:"admin"`
+	}{})
+	require.NoError(t, err)
+
+	require.True(t, vars[0].Secret)
+	require.Equal(t, "******", vars[0].Default)
+	require.True(t, vars[1].Secret)
+	require.False(t, vars[2].Secret)
+	require.Equal(t, "admin", vars[2].Default)
+}
@@ -0,0 +1,220 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// Source provides raw values for config keys.
+type Source interface {
+	// Lookup returns value of the key and reports whether the key is present.
+	Lookup(key string) (string, bool)
+}
+
+// KeyLister is implemented by sources which can list their keys.
+// Listing is required to discover keys of maps of structs like TENANTS_<NAME>_HOST.
+type KeyLister interface {
+	Keys() []string
+}
+
+// Originer is implemented by sources which can tell where the key is defined, e.g. file and line.
+// It's used in provenance report, see WithProvenance.
+type Originer interface {
+	Origin(key string) string
+}
+
+// ListSource is implemented by sources which values can be lists, like arrays of JSON files.
+// Elements of the list are joined with `sep` of the field, so arrays don't depend on the separator.
+type ListSource interface {
+	LookupList(key string) ([]string, bool)
+}
+
+// sourceList returns elements of the key if source has it as a list.
+func sourceList(source Source, key string) ([]string, bool) {
+	if s, ok := source.(ListSource); ok {
+		return s.LookupList(key)
+	}
+	return nil, false
+}
+
+// OSSource looks up keys in process environment.
+type OSSource struct{}
+
+func (OSSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (OSSource) Origin(string) string {
+	return "env"
+}
+
+func (OSSource) Keys() []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// MapSource looks up keys in the map. Useful for tests, which can't share process environment.
+type MapSource map[string]string
+
+func (m MapSource) Lookup(key string) (string, bool) {
+	value, ok := m[key]
+	return value, ok
+}
+
+func (m MapSource) Origin(string) string {
+	return "map"
+}
+
+// Expands reports that values of the map are expanded, they are usually written by hand or read from files.
+func (m MapSource) Expands(string) bool {
+	return true
+}
+
+func (m MapSource) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ChainSource looks up keys in sources one by one.
+// The first source that has the key wins, so sources should be ordered by precedence:
+//
+//	env.ChainSource{env.OSSource{}, dotEnvSource}
+type ChainSource []Source
+
+func (c ChainSource) Lookup(key string) (string, bool) {
+	for _, source := range c {
+		if value, ok := source.Lookup(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Origin returns origin of the key in the first source that has it.
+func (c ChainSource) Origin(key string) string {
+	for _, source := range c {
+		if _, ok := source.Lookup(key); ok {
+			return sourceOrigin(source, key)
+		}
+	}
+	return ""
+}
+
+// Expands delegates to the first source that has the key.
+func (c ChainSource) Expands(key string) bool {
+	for _, source := range c {
+		if _, ok := source.Lookup(key); ok {
+			return sourceExpands(source, key)
+		}
+	}
+	return false
+}
+
+// LookupList delegates to the first source that has the key.
+func (c ChainSource) LookupList(key string) ([]string, bool) {
+	for _, source := range c {
+		if _, ok := source.Lookup(key); ok {
+			return sourceList(source, key)
+		}
+	}
+	return nil, false
+}
+
+// sourceOrigin returns origin of the key in source or type of source if it doesn't implement Originer.
+func sourceOrigin(source Source, key string) string {
+	if originer, ok := source.(Originer); ok {
+		return originer.Origin(key)
+	}
+	return fmt.Sprintf("%T", source)
+}
+
+// Keys returns unique keys of the sources which implement KeyLister.
+func (c ChainSource) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, source := range c {
+		lister, ok := source.(KeyLister)
+		if !ok {
+			continue
+		}
+		for _, key := range lister.Keys() {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// DotEnvSource reads .env file at path without touching process environment.
+// Values are not expanded, references are expanded by ParseConfigFrom,
+// so they can point to variables of other sources. $ in single-quoted values is escaped as $$ to keep them literal.
+func DotEnvSource(path string) (*FileSource, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to read %s", path)
+	}
+
+	entries, err := decryptDotEnv(file, &decrypter{lookup: os.LookupEnv})
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to parse %s", path)
+	}
+
+	source := &FileSource{
+		Path:   path,
+		Values: make(MapSource, len(entries)),
+		Lines:  make(map[string]int, len(entries)),
+	}
+	for _, entry := range entries {
+		source.Values[entry.Name] = entry.sourceValue()
+		source.Lines[entry.Name] = entry.Line
+	}
+
+	return source, nil
+}
+
+// FileSource holds variables read from a file.
+type FileSource struct {
+	Path   string
+	Values MapSource
+	Lines  map[string]int      // line numbers where keys are defined, if known
+	Lists  map[string][]string // elements of arrays, Values hold them joined with comma
+}
+
+func (f *FileSource) Lookup(key string) (string, bool) {
+	return f.Values.Lookup(key)
+}
+
+func (f *FileSource) Expands(string) bool {
+	return true
+}
+
+func (f *FileSource) LookupList(key string) ([]string, bool) {
+	elems, ok := f.Lists[key]
+	return elems, ok
+}
+
+func (f *FileSource) Keys() []string {
+	return f.Values.Keys()
+}
+
+// Origin returns path of the file and line of the key: .env:12
+func (f *FileSource) Origin(key string) string {
+	if line, ok := f.Lines[key]; ok {
+		return f.Path + ":" + strconv.Itoa(line)
+	}
+	return f.Path
+}
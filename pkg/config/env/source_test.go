@@ -0,0 +1,128 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+func TestParseConfigFrom(t *testing.T) {
+	t.Run("map source", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &testConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"DB_HOST":       "localhost",
+			"KAFKA_BROKERS": "localhost:9092",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "localhost", cfg.Db.Host)
+		require.Equal(t, 5432, cfg.Db.Port) // Default value
+		require.EqualValues(t, []string{"localhost:9092"}, cfg.Kafka.Brokers)
+	})
+
+	t.Run("first source wins", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &testConfig{}
+		err := ParseConfigFrom(cfg,
+			MapSource{"DB_HOST": "override"},
+			MapSource{"DB_HOST": "localhost", "DB_PORT": "6432"},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, "override", cfg.Db.Host)
+		require.Equal(t, 6432, cfg.Db.Port)
+	})
+
+	t.Run("no sources", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &testConfig{}
+		err := ParseConfigFrom(cfg)
+		require.ErrorIs(t, err, ErrRequired)
+	})
+}
+
+func TestChainSource(t *testing.T) {
+	source := ChainSource{
+		MapSource{"A": "1"},
+		MapSource{"A": "2", "B": "3"},
+	}
+
+	value, ok := source.Lookup("A")
+	require.True(t, ok)
+	require.Equal(t, "1", value)
+
+	value, ok = source.Lookup("B")
+	require.True(t, ok)
+	require.Equal(t, "3", value)
+
+	_, ok = source.Lookup("C")
+	require.False(t, ok)
+}
+
+func TestOSSource(t *testing.T) {
+	prepareEnv(t, "GOSTDLIB_OS_SOURCE", "value")
+
+	value, ok := OSSource{}.Lookup("GOSTDLIB_OS_SOURCE")
+	require.True(t, ok)
+	require.Equal(t, "value", value)
+
+	_, ok = OSSource{}.Lookup("GOSTDLIB_OS_SOURCE_MISSING")
+	require.False(t, ok)
+}
+
+func TestDotEnvSource(t *testing.T) {
+	t.Run("file is parsed without touching process env", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := os.WriteFile(path, []byte("DB_HOST=localhost\nDB_PORT=6432 # comment\n"), 0o600)
+		require.NoError(t, err)
+
+		source, err := DotEnvSource(path)
+		require.NoError(t, err)
+		require.EqualValues(t, MapSource{"DB_HOST": "localhost", "DB_PORT": "6432"}, source.Values)
+		require.Equal(t, path+":2", source.Origin("DB_PORT"))
+
+		_, ok := os.LookupEnv("DB_HOST")
+		require.False(t, ok)
+	})
+
+	t.Run("single-quoted values are not expanded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		err := os.WriteFile(path, []byte("DB_HOST='${HOST}'\nKAFKA_BROKERS=\"${DB_HOST}\"\n"), 0o600)
+		require.NoError(t, err)
+
+		source, err := DotEnvSource(path)
+		require.NoError(t, err)
+
+		cfg := &testConfig{}
+		require.NoError(t, ParseConfigFrom(cfg, source))
+		require.Equal(t, "${HOST}", cfg.Db.Host)
+		require.EqualValues(t, []string{"${HOST}"}, cfg.Kafka.Brokers)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := DotEnvSource(filepath.Join(t.TempDir(), ".env"))
+		require.Error(t, err)
+	})
+}
+
+func TestKeyLister(t *testing.T) {
+	source := ChainSource{
+		MapSource{"A": "1"},
+		lookupOnly{"C": "4"},
+		MapSource{"A": "2", "B": "3"},
+	}
+
+	keys := source.Keys()
+	slices.Sort(keys)
+	require.EqualValues(t, []string{"A", "B"}, keys)
+
+	prepareEnv(t, "GOSTDLIB_KEY_LISTER", "1")
+	require.True(t, slices.Contains(OSSource{}.Keys(), "GOSTDLIB_KEY_LISTER"))
+}
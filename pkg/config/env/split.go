@@ -0,0 +1,119 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	defaultSep   = ","
+	defaultKVSep = ":"
+)
+
+// splitEscaped splits s by sep, ignoring separators escaped with backslash.
+// Escape sequences are kept in parts, so parts can be cut by another separator later.
+func splitEscaped(s, sep string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i += 2
+		case strings.HasPrefix(s[i:], sep):
+			parts = append(parts, s[start:i])
+			i += len(sep)
+			start = i
+		default:
+			i++
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// joinEscaped joins parts with sep, separators and backslashes in parts are escaped, so splitEscaped restores them.
+func joinEscaped(parts []string, sep string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = escape(part, sep)
+	}
+	return strings.Join(escaped, sep)
+}
+
+// splitElements splits raw value of slice the same way setFieldValue does.
+func splitElements(raw string, tag reflect.StructTag) []string {
+	if raw == "" {
+		return nil
+	}
+	sep := tagOrDefault(tag, "sep", defaultSep)
+	parts := splitEscaped(raw, sep)
+	for i, part := range parts {
+		parts[i] = unescape(strings.TrimSpace(part), sep)
+	}
+	return parts
+}
+
+// cutEscaped is strings.Cut, which ignores separators escaped with backslash.
+func cutEscaped(s, sep string) (before, after string, found bool) {
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i += 2
+		case strings.HasPrefix(s[i:], sep):
+			return s[:i], s[i+len(sep):], true
+		default:
+			i++
+		}
+	}
+	return s, "", false
+}
+
+// unescape removes backslashes placed before separators or before another backslash.
+// Other backslashes are kept as is, so values like `C:\dir` don't need escaping.
+func unescape(s string, seps ...string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isEscaped(s[i+1:], seps) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// escape is the inverse of unescape: it puts backslash before separators and backslashes.
+func escape(s string, seps ...string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if isEscaped(s[i:], seps) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+func isEscaped(s string, seps []string) bool {
+	if s[0] == '\\' {
+		return true
+	}
+	for _, sep := range seps {
+		if strings.HasPrefix(s, sep) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOrDefault returns value of the tag or def if tag is not set.
+func tagOrDefault(tag reflect.StructTag, key, def string) string {
+	if value := tag.Get(key); value != "" {
+		return value
+	}
+	return def
+}
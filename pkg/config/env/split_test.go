@@ -0,0 +1,46 @@
+package env
+
+import (
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+func Test_splitEscaped(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		sep      string
+		expected []string
+	}{
+		{name: "single", input: "a", sep: ",", expected: []string{"a"}},
+		{name: "several", input: "a,b,c", sep: ",", expected: []string{"a", "b", "c"}},
+		{name: "empty parts", input: ",a,", sep: ",", expected: []string{"", "a", ""}},
+		{name: "escaped sep", input: `a\,b,c`, sep: ",", expected: []string{`a\,b`, "c"}},
+		{name: "escaped backslash", input: `a\\,b`, sep: ",", expected: []string{`a\\`, "b"}},
+		{name: "multichar sep", input: "a::b::c", sep: "::", expected: []string{"a", "b", "c"}},
+		{name: "trailing backslash", input: `a,b\`, sep: ",", expected: []string{"a", `b\`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.EqualValues(t, tt.expected, splitEscaped(tt.input, tt.sep))
+		})
+	}
+}
+
+func Test_cutEscaped(t *testing.T) {
+	before, after, ok := cutEscaped(`a\:b:c:d`, ":")
+	require.True(t, ok)
+	require.Equal(t, `a\:b`, before)
+	require.Equal(t, "c:d", after)
+
+	_, _, ok = cutEscaped(`a\:b`, ":")
+	require.False(t, ok)
+}
+
+func Test_unescape(t *testing.T) {
+	require.Equal(t, "a,b", unescape(`a\,b`, ","))
+	require.Equal(t, `a\b`, unescape(`a\\b`, ","))
+	require.Equal(t, `C:\dir`, unescape(`C:\dir`, ","))
+	require.Equal(t, "a:b,c", unescape(`a\:b\,c`, ",", ":"))
+}
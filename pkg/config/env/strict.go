@@ -0,0 +1,141 @@
+package env
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// ErrUnknownKey is reported in strict mode for variables which don't map to any field of the config.
+var ErrUnknownKey = errs.New("unknown environment variable")
+
+// Strict makes parser fail on variables with the prefix which don't map to any field of the config.
+// Keys referenced by other values with ${VAR} are considered known.
+// Sources must implement KeyLister, so parser can find all variables with the prefix.
+func Strict(prefix string) Option {
+	return func(o *options) {
+		o.strict = true
+		o.strictPrefix = prefix
+	}
+}
+
+// recordingSource remembers keys looked up by parser, every key that maps to a field is looked up at least once.
+type recordingSource struct {
+	source Source
+	keys   map[string]bool
+}
+
+func (s *recordingSource) Lookup(key string) (string, bool) {
+	s.keys[key] = true
+	return s.source.Lookup(key)
+}
+
+func (s *recordingSource) Keys() []string {
+	if lister, ok := s.source.(KeyLister); ok {
+		return lister.Keys()
+	}
+	return nil
+}
+
+func (s *recordingSource) Origin(key string) string {
+	return sourceOrigin(s.source, key)
+}
+
+func (s *recordingSource) Expands(key string) bool {
+	return sourceExpands(s.source, key)
+}
+
+func (s *recordingSource) LookupList(key string) ([]string, bool) {
+	return sourceList(s.source, key)
+}
+
+// checkUnknownKeys reports keys with the prefix which parser didn't look up.
+func (p *parser) checkUnknownKeys(prefix string, recorder *recordingSource) {
+	if !listsAllKeys(recorder.source) {
+		p.fail("", prefix, "", errs.New("strict mode requires sources which implement KeyLister"))
+		return
+	}
+	lister := recorder.source.(KeyLister)
+
+	var known []string
+	for key := range recorder.keys {
+		if strings.HasPrefix(key, prefix) {
+			known = append(known, key)
+		}
+	}
+
+	var unknown []string
+	for _, key := range lister.Keys() {
+		if strings.HasPrefix(key, prefix) && !recorder.keys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+
+	for _, key := range unknown {
+		if suggestion, ok := closestKey(key, known); ok {
+			p.fail("", key, "", errs.Newf("%w, did you mean %s?", ErrUnknownKey, suggestion))
+			continue
+		}
+		p.fail("", key, "", ErrUnknownKey)
+	}
+}
+
+// listsAllKeys reports whether source and all sources it's chained of implement KeyLister.
+func listsAllKeys(source Source) bool {
+	chain, ok := source.(ChainSource)
+	if !ok {
+		_, ok := source.(KeyLister)
+		return ok
+	}
+	for _, s := range chain {
+		if !listsAllKeys(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// closestKey returns key from candidates with the smallest edit distance to key,
+// if the distance is small enough to be a typo.
+func closestKey(key string, candidates []string) (string, bool) {
+	maxDistance := max(2, len(key)/10)
+
+	best, bestDistance := "", maxDistance+1
+	for _, candidate := range candidates {
+		distance := editDistance(key, candidate)
+		if distance < bestDistance || distance == bestDistance && candidate < best {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best, best != ""
+}
+
+// editDistance is Damerau-Levenshtein distance (optimal string alignment):
+// number of insertions, deletions, substitutions and transpositions of adjacent characters to turn a into b.
+func editDistance(a, b string) int {
+	// rows i-2, i-1 and i of the distance matrix
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,110 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type strictConfig struct {
+	Svc struct {
+		Db       DbConfig            `env:"DB"`
+		Cache    *CacheConfig        `env:"CACHE"`
+		Password Secret[string]      `env:"PASSWORD"`
+		URL      string              `env:"URL"`
+		Tenants  map[string]DbConfig `env:"TENANTS"`
+	} `env:"MYSVC"`
+}
+
+func TestParseConfig_Strict(t *testing.T) {
+	t.Run("known keys", func(t *testing.T) {
+		t.Parallel()
+
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(passwordFile, []byte("secret"), 0o600))
+
+		cfg := &strictConfig{}
+		err := ParseConfig(cfg, Strict("MYSVC_"), FromSources(MapSource{
+			"MYSVC_DB_HOST":           "localhost",
+			"MYSVC_CACHE_ADDR":        "cache:6379",
+			"MYSVC_PASSWORD_FILE":     passwordFile,
+			"MYSVC_BASE":              "http://localhost",
+			"MYSVC_URL":               "${MYSVC_BASE}/api",
+			"MYSVC_TENANTS_ACME_HOST": "acme",
+			"OTHER_KEY":               "ignored",
+		}))
+		require.NoError(t, err)
+		require.Equal(t, "http://localhost/api", cfg.Svc.URL)
+	})
+
+	t.Run("unknown keys", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &strictConfig{}
+		err := ParseConfig(cfg, Strict("MYSVC_"), FromSources(MapSource{
+			"MYSVC_DB_HSOT":               "localhost",
+			"MYSVC_CACHE_ADRR":            "cache:6379",
+			"MYSVC_TENANTS_ACME_HOST":     "acme",
+			"MYSVC_TENANTS_ACME_PASSWORD": "secret",
+			"MYSVC_SOMETHING_ELSE":        "value",
+		}))
+		require.ErrorIs(t, err, ErrUnknownKey)
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+
+		messages := make(map[string]string)
+		for _, fieldErr := range configErr.Errors {
+			if errs.Is(fieldErr, ErrUnknownKey) {
+				messages[fieldErr.Key] = fieldErr.Err.Error()
+			}
+		}
+		require.EqualValues(t, map[string]string{
+			"MYSVC_CACHE_ADRR":            "unknown environment variable, did you mean MYSVC_CACHE_ADDR?",
+			"MYSVC_DB_HSOT":               "unknown environment variable, did you mean MYSVC_DB_HOST?",
+			"MYSVC_SOMETHING_ELSE":        "unknown environment variable",
+			"MYSVC_TENANTS_ACME_PASSWORD": "unknown environment variable",
+		}, messages)
+	})
+
+	t.Run("source without key listing", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &strictConfig{}
+		err := ParseConfig(cfg, Strict("MYSVC_"), FromSources(lookupOnly{"MYSVC_DB_HOST": "localhost"}))
+		require.Error(t, err)
+	})
+
+	t.Run("not strict by default", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := &strictConfig{}
+		err := ParseConfigFrom(cfg, MapSource{"MYSVC_DB_HOST": "localhost", "MYSVC_DB_HSOT": "localhost"})
+		require.NoError(t, err)
+	})
+}
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"DB_HOST", "DB_HOST", 0},
+		{"DB_HSOT", "DB_HOST", 1},
+		{"DB_HOS", "DB_HOST", 1},
+		{"DB_HOSTT", "DB_HOST", 1},
+		{"DB_POST", "DB_HOST", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			require.Equal(t, tt.distance, editDistance(tt.a, tt.b))
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package env
+
+import (
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// typeParser parses env value into a value of the exact registered type.
+type typeParser func(value string, tag reflect.StructTag) (any, error)
+
+// typeParsers holds parsers for standard library types that can't be parsed by their kind.
+// Pointer types are registered when the value type shouldn't be copied.
+var typeParsers = map[reflect.Type]typeParser{
+	reflect.TypeFor[time.Duration](): func(value string, _ reflect.StructTag) (any, error) {
+		return time.ParseDuration(value)
+	},
+	reflect.TypeFor[time.Time](): parseTime,
+	reflect.TypeFor[*url.URL](): func(value string, _ reflect.StructTag) (any, error) {
+		return url.Parse(value)
+	},
+	reflect.TypeFor[url.URL](): func(value string, _ reflect.StructTag) (any, error) {
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+	reflect.TypeFor[net.IP](): func(value string, _ reflect.StructTag) (any, error) {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, errs.Newf("invalid IP address %q", value)
+		}
+		return ip, nil
+	},
+	reflect.TypeFor[netip.Addr](): func(value string, _ reflect.StructTag) (any, error) {
+		return netip.ParseAddr(value)
+	},
+	reflect.TypeFor[netip.AddrPort](): func(value string, _ reflect.StructTag) (any, error) {
+		return netip.ParseAddrPort(value)
+	},
+	reflect.TypeFor[netip.Prefix](): func(value string, _ reflect.StructTag) (any, error) {
+		return netip.ParsePrefix(value)
+	},
+	reflect.TypeFor[*regexp.Regexp](): func(value string, _ reflect.StructTag) (any, error) {
+		return regexp.Compile(value)
+	},
+	reflect.TypeFor[*big.Int](): func(value string, _ reflect.StructTag) (any, error) {
+		n, ok := new(big.Int).SetString(value, 0)
+		if !ok {
+			return nil, errs.Newf("invalid integer %q", value)
+		}
+		return n, nil
+	},
+	reflect.TypeFor[*big.Float](): func(value string, _ reflect.StructTag) (any, error) {
+		f, _, err := big.ParseFloat(value, 10, 0, big.ToNearestEven)
+		return f, err
+	},
+}
+
+// typeFormatter formats value of the registered type the way typeParser parses it.
+type typeFormatter func(value any, tag reflect.StructTag) string
+
+// typeFormatters are the inverse of typeParsers, used by Marshal.
+var typeFormatters = map[reflect.Type]typeFormatter{
+	reflect.TypeFor[time.Duration](): func(value any, _ reflect.StructTag) string {
+		return value.(time.Duration).String()
+	},
+	reflect.TypeFor[time.Time](): func(value any, tag reflect.StructTag) string {
+		// time.Parse accepts fractional seconds in time.RFC3339 layout, so they aren't lost
+		return value.(time.Time).Format(tagOrDefault(tag, "layout", time.RFC3339Nano))
+	},
+	reflect.TypeFor[*url.URL](): func(value any, _ reflect.StructTag) string {
+		return value.(*url.URL).String()
+	},
+	reflect.TypeFor[url.URL](): func(value any, _ reflect.StructTag) string {
+		u := value.(url.URL)
+		return u.String()
+	},
+	reflect.TypeFor[net.IP](): func(value any, _ reflect.StructTag) string {
+		return value.(net.IP).String()
+	},
+	reflect.TypeFor[netip.Addr](): func(value any, _ reflect.StructTag) string {
+		return value.(netip.Addr).String()
+	},
+	reflect.TypeFor[netip.AddrPort](): func(value any, _ reflect.StructTag) string {
+		return value.(netip.AddrPort).String()
+	},
+	reflect.TypeFor[netip.Prefix](): func(value any, _ reflect.StructTag) string {
+		return value.(netip.Prefix).String()
+	},
+	reflect.TypeFor[*regexp.Regexp](): func(value any, _ reflect.StructTag) string {
+		return value.(*regexp.Regexp).String()
+	},
+	reflect.TypeFor[*big.Int](): func(value any, _ reflect.StructTag) string {
+		return value.(*big.Int).String()
+	},
+	reflect.TypeFor[*big.Float](): func(value any, _ reflect.StructTag) string {
+		return value.(*big.Float).Text('g', -1)
+	},
+}
+
+// parseTime parses time in the layout from `layout` tag, time.RFC3339 by default.
+func parseTime(value string, tag reflect.StructTag) (any, error) {
+	layout := tag.Get("layout")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return time.Parse(layout, value)
+}
+
+// setKnownType sets v using a parser from typeParsers.
+// Reports false if there is no parser for the type of v.
+func setKnownType(v reflect.Value, envValue string, tag reflect.StructTag) (bool, error) {
+	parse, ok := typeParsers[v.Type()]
+	if !ok {
+		return false, nil
+	}
+
+	parsed, err := parse(envValue, tag)
+	if err != nil {
+		return true, errs.Wrapf(err, "failed to parse %s value", v.Type())
+	}
+	v.Set(reflect.ValueOf(parsed))
+	return true, nil
+}
+
+// hasTypeParser reports whether t or pointer to t is parsed by typeParsers.
+func hasTypeParser(t reflect.Type) bool {
+	if _, ok := typeParsers[t]; ok {
+		return true
+	}
+	_, ok := typeParsers[reflect.PointerTo(t)]
+	return ok
+}
+
+// ByteSize is a size in bytes, which can be parsed from human readable values:
+// 512, 512B, 64KB, 64KiB, 1.5GB, 10MiB.
+// Decimal units (KB, MB, GB, TB, PB) are powers of 1000, binary units (KiB, MiB, GiB, TiB, PiB) are powers of 1024.
+// Units are case insensitive.
+type ByteSize uint64
+
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"pb":  PB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+	"pib": PiB,
+}
+
+// ParseByteSize parses human readable size, see ByteSize for supported formats.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	numberEnd := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if numberEnd == -1 {
+		numberEnd = len(s)
+	}
+
+	number, unit := s[:numberEnd], strings.ToLower(strings.TrimSpace(s[numberEnd:]))
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, errs.Newf("unknown size unit %q", unit)
+	}
+
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64/uint64(multiplier) {
+			return 0, errs.Newf("size %q overflows uint64", s)
+		}
+		return ByteSize(n) * multiplier, nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, errs.Wrapf(err, "failed to parse size %q", s)
+	}
+	size := f * float64(multiplier)
+	if size >= math.MaxUint64 {
+		return 0, errs.Newf("size %q overflows uint64", s)
+	}
+	return ByteSize(size), nil
+}
+
+func (s *ByteSize) UnmarshalEnv(v string) error {
+	size, err := ParseByteSize(v)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+func (s ByteSize) MarshalEnv() (string, error) {
+	return s.String(), nil
+}
+
+// String formats size with the largest binary unit that represents it exactly: 64MiB, 1500B.
+func (s ByteSize) String() string {
+	units := []struct {
+		size ByteSize
+		name string
+	}{
+		{PiB, "PiB"}, {TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"},
+	}
+	for _, unit := range units {
+		if s >= unit.size && s%unit.size == 0 {
+			return strconv.FormatUint(uint64(s/unit.size), 10) + unit.name
+		}
+	}
+	return strconv.FormatUint(uint64(s), 10) + "B"
+}
@@ -0,0 +1,141 @@
+package env
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type typesConfig struct {
+	Timeout    time.Duration   `env:"TIMEOUT"`
+	Started    time.Time       `env:"STARTED"`
+	Date       time.Time       `env:"DATE" layout:"2006-01-02"`
+	Endpoint   *url.URL        `env:"ENDPOINT"`
+	Callback   url.URL         `env:"CALLBACK"`
+	IP         net.IP          `env:"IP"`
+	Addr       netip.Addr      `env:"ADDR"`
+	AddrPort   netip.AddrPort  `env:"ADDR_PORT"`
+	Subnet     netip.Prefix    `env:"SUBNET"`
+	Pattern    *regexp.Regexp  `env:"PATTERN"`
+	BigInt     *big.Int        `env:"BIG_INT"`
+	BigFloat   *big.Float      `env:"BIG_FLOAT"`
+	MaxBody    ByteSize        `env:"MAX_BODY"`
+	Timeouts   []time.Duration `env:"TIMEOUTS"`
+	OptTimeout *time.Duration  `env:"OPT_TIMEOUT"`
+}
+
+func TestParseConfig_Types(t *testing.T) {
+	t.Run("valid values", func(t *testing.T) {
+		cfg := &typesConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"TIMEOUT":     "10s",
+			"STARTED":     "2024-05-01T10:00:00Z",
+			"DATE":        "2024-05-01",
+			"ENDPOINT":    "https://example.com/api?x=1",
+			"CALLBACK":    "http://localhost:8080/cb",
+			"IP":          "10.0.0.1",
+			"ADDR":        "::1",
+			"ADDR_PORT":   "127.0.0.1:8080",
+			"SUBNET":      "10.0.0.0/8",
+			"PATTERN":     "^a+b$",
+			"BIG_INT":     "123456789012345678901234567890",
+			"BIG_FLOAT":   "1.5e100",
+			"MAX_BODY":    "64MiB",
+			"TIMEOUTS":    "1s,2m",
+			"OPT_TIMEOUT": "1h",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, 10*time.Second, cfg.Timeout)
+		require.True(t, cfg.Started.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)))
+		require.True(t, cfg.Date.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)))
+		require.Equal(t, "https://example.com/api?x=1", cfg.Endpoint.String())
+		require.Equal(t, "localhost:8080", cfg.Callback.Host)
+		require.True(t, cfg.IP.Equal(net.IPv4(10, 0, 0, 1)))
+		require.Equal(t, netip.IPv6Loopback(), cfg.Addr)
+		require.Equal(t, netip.MustParseAddrPort("127.0.0.1:8080"), cfg.AddrPort)
+		require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), cfg.Subnet)
+		require.True(t, cfg.Pattern.MatchString("aaab"))
+		require.Equal(t, "123456789012345678901234567890", cfg.BigInt.String())
+		require.Equal(t, "1.5e+100", cfg.BigFloat.String())
+		require.Equal(t, 64*MiB, cfg.MaxBody)
+		require.EqualValues(t, []time.Duration{time.Second, 2 * time.Minute}, cfg.Timeouts)
+		require.Equal(t, time.Hour, *cfg.OptTimeout)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		cfg := &typesConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"TIMEOUT":   "10",
+			"STARTED":   "2024-05-01",
+			"DATE":      "01.05.2024",
+			"ENDPOINT":  "://",
+			"IP":        "10.0.0",
+			"ADDR":      "localhost",
+			"ADDR_PORT": "127.0.0.1",
+			"SUBNET":    "10.0.0.0",
+			"PATTERN":   "(",
+			"BIG_INT":   "12a",
+			"BIG_FLOAT": "x",
+			"MAX_BODY":  "64XB",
+		})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 12, len(configErr.Errors))
+	})
+
+	t.Run("unset pointer stays nil", func(t *testing.T) {
+		cfg := &typesConfig{}
+		err := ParseConfigFrom(cfg, MapSource{})
+		require.NoError(t, err)
+
+		require.Nil(t, cfg.Endpoint)
+		require.Nil(t, cfg.OptTimeout)
+	})
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ByteSize
+	}{
+		{"512", 512},
+		{"512B", 512},
+		{"64KB", 64 * KB},
+		{"64KiB", 64 * KiB},
+		{"64mib", 64 * MiB},
+		{"1.5GB", 1500 * MB},
+		{"1.5 GiB", 1536 * MiB},
+		{"2TiB", 2 * TiB},
+		{"1PB", PB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			size, err := ParseByteSize(tt.input)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, size)
+		})
+	}
+
+	for _, input := range []string{"", "MiB", "64XB", "1.2.3KB", "100000PiB"} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := ParseByteSize(input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestByteSize_String(t *testing.T) {
+	require.Equal(t, "64MiB", (64 * MiB).String())
+	require.Equal(t, "1536MiB", (1536 * MiB).String())
+	require.Equal(t, "1500B", (1500 * Byte).String())
+	require.Equal(t, "0B", ByteSize(0).String())
+}
@@ -0,0 +1,222 @@
+package env
+
+import (
+	"cmp"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// ErrInvalid is reported for values that don't pass validation
+var ErrInvalid = errs.New("invalid value")
+
+// Validator can be implemented by config struct or any of its nested structs.
+// Validate is called after all fields of the config are parsed without errors,
+// nested structs are validated before their parents.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeFor[Validator]()
+
+// validators are checks available in `validate` tag
+var validators = map[string]func(value string) error{
+	"url":      validateURL,
+	"port":     validatePort,
+	"hostport": validateHostPort,
+}
+
+// validateField checks parsed value v and its raw value against validation tags:
+//
+//	min:"1" max:"10"     - bounds of numbers and durations, or bounds of length of strings, slices and maps
+//	oneof:"a,b,c"        - raw value must be one of the listed values
+//	regex:"^[a-z]+$"     - raw value must match regular expression
+//	validate:"url,port"  - raw value must pass named checks: url, port, hostport
+//
+// oneof, regex and validate check every element of slices separately.
+func validateField(v reflect.Value, raw string, tag reflect.StructTag) error {
+	v = unwrapSecret(v)
+
+	if err := validateRange(v, tag); err != nil {
+		return err
+	}
+
+	_, hasOneOf := tag.Lookup("oneof")
+	_, hasRegex := tag.Lookup("regex")
+	_, hasValidate := tag.Lookup("validate")
+	if !hasOneOf && !hasRegex && !hasValidate {
+		return nil
+	}
+
+	elements := []string{raw}
+	if v.Kind() == reflect.Slice && !hasUnmarshaler(v.Type()) {
+		elements = splitElements(raw, tag)
+	}
+
+	for _, element := range elements {
+		if err := validateRaw(element, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRaw(raw string, tag reflect.StructTag) error {
+	if oneOf, ok := tag.Lookup("oneof"); ok {
+		options := strings.Split(oneOf, ",")
+		if !slices.Contains(options, raw) {
+			return errs.Newf("%w: must be one of [%s]", ErrInvalid, strings.Join(options, ", "))
+		}
+	}
+
+	if pattern, ok := tag.Lookup("regex"); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return errs.Wrapf(err, "invalid regex tag %q", pattern)
+		}
+		if !re.MatchString(raw) {
+			return errs.Newf("%w: must match %s", ErrInvalid, pattern)
+		}
+	}
+
+	if names, ok := tag.Lookup("validate"); ok {
+		for _, name := range strings.Split(names, ",") {
+			validate, ok := validators[strings.TrimSpace(name)]
+			if !ok {
+				return errs.Newf("unknown validator %q", name)
+			}
+			if err := validate(raw); err != nil {
+				return errs.Newf("%w: %v", ErrInvalid, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateRange(v reflect.Value, tag reflect.StructTag) error {
+	if bound, ok := tag.Lookup("min"); ok {
+		res, isLen, err := compareWith(v, bound, tag)
+		if err != nil {
+			return errs.Wrapf(err, "invalid min tag %q", bound)
+		}
+		if res < 0 {
+			return errs.Newf("%w: %s be at least %s", ErrInvalid, mustOrLength(isLen), bound)
+		}
+	}
+
+	if bound, ok := tag.Lookup("max"); ok {
+		res, isLen, err := compareWith(v, bound, tag)
+		if err != nil {
+			return errs.Wrapf(err, "invalid max tag %q", bound)
+		}
+		if res > 0 {
+			return errs.Newf("%w: %s be at most %s", ErrInvalid, mustOrLength(isLen), bound)
+		}
+	}
+
+	return nil
+}
+
+func mustOrLength(isLen bool) string {
+	if isLen {
+		return "length must"
+	}
+	return "must"
+}
+
+// compareWith compares v with bound parsed into the type of v.
+// Strings, slices and maps are compared by length, isLen reports that.
+func compareWith(v reflect.Value, bound string, tag reflect.StructTag) (res int, isLen bool, err error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		n, err := strconv.Atoi(bound)
+		if err != nil {
+			return 0, true, err
+		}
+		return cmp.Compare(v.Len(), n), true, nil
+	}
+
+	b := reflect.New(v.Type()).Elem()
+	if err := setFieldValue(b, bound, tag); err != nil {
+		return 0, false, err
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(v.Int(), b.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(v.Uint(), b.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(v.Float(), b.Float()), false, nil
+	default:
+		return 0, false, errs.Newf("%w %s for bounds", ErrUnsupportedType, v.Type())
+	}
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return errs.Newf("%q is not an absolute URL", value)
+	}
+	return nil
+}
+
+func validatePort(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return errs.Newf("%q is not a port in range 1..65535", value)
+	}
+	return nil
+}
+
+func validateHostPort(value string) error {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return errs.Newf("%q has no host", value)
+	}
+	return validatePort(port)
+}
+
+// structRef is a nested struct of the config remembered for Validate call.
+type structRef struct {
+	value  reflect.Value
+	prefix string
+	path   string
+}
+
+// rootLabel names root struct in errors of its Validate method, it has neither key prefix nor path.
+const rootLabel = "(root)"
+
+// runValidators calls Validate of collected structs, failures are reported with key prefix of the struct.
+func (p *parser) runValidators() {
+	for _, ref := range p.validators {
+		validator := ref.value.Addr().Interface().(Validator)
+		if err := validator.Validate(); err != nil {
+			path, prefix := ref.path, ref.prefix
+			if path == "" {
+				path, prefix = rootLabel, rootLabel
+			}
+			p.fail(path, prefix, "", err)
+		}
+	}
+}
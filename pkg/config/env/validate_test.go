@@ -0,0 +1,177 @@
+package env
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type validatedConfig struct {
+	Port     int           `env:"PORT" min:"1" max:"65535"`
+	Timeout  time.Duration `env:"TIMEOUT" min:"1s" max:"1m"`
+	Name     string        `env:"NAME" min:"3" max:"5"`
+	Level    string        `env:"LEVEL" oneof:"debug,info,warn"`
+	Brokers  []string      `env:"BROKERS" validate:"hostport" min:"1"`
+	Endpoint string        `env:"ENDPOINT" validate:"url"`
+	Code     string        `env:"CODE" regex:"^[A-Z]{3}$"`
+	APIPort  string        `env:"API_PORT" validate:"port"`
+	Limit    ByteSize      `env:"LIMIT" max:"1MiB"`
+}
+
+func TestParseConfig_Validation(t *testing.T) {
+	t.Run("valid values", func(t *testing.T) {
+		cfg := &validatedConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PORT":     "8080",
+			"TIMEOUT":  "30s",
+			"NAME":     "app",
+			"LEVEL":    "info",
+			"BROKERS":  "localhost:9092, kafka:9093",
+			"ENDPOINT": "https://example.com/api",
+			"CODE":     "ABC",
+			"API_PORT": "443",
+			"LIMIT":    "512KiB",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		cfg := &validatedConfig{}
+		err := ParseConfigFrom(cfg, MapSource{
+			"PORT":     "0",
+			"TIMEOUT":  "2m",
+			"NAME":     "ab",
+			"LEVEL":    "trace",
+			"BROKERS":  "localhost:9092,kafka",
+			"ENDPOINT": "/api",
+			"CODE":     "abc",
+			"API_PORT": "70000",
+			"LIMIT":    "2MiB",
+		})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 9, len(configErr.Errors))
+		for _, fieldErr := range configErr.Errors {
+			require.ErrorIs(t, fieldErr, ErrInvalid)
+		}
+		require.Equal(t, "invalid value: must be at least 1", configErr.Errors[0].Err.Error())
+		require.Equal(t, "invalid value: must be at most 1m", configErr.Errors[1].Err.Error())
+		require.Equal(t, "invalid value: length must be at least 3", configErr.Errors[2].Err.Error())
+		require.Equal(t, "invalid value: must be one of [debug, info, warn]", configErr.Errors[3].Err.Error())
+	})
+
+	t.Run("defaults are validated", func(t *testing.T) {
+		cfg := &struct {
+			Port int `env:"PORT" default:"0" min:"1"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{})
+		require.ErrorIs(t, err, ErrInvalid)
+	})
+
+	t.Run("unset fields are validated as zero values", func(t *testing.T) {
+		cfg := &validatedConfig{}
+		err := ParseConfigFrom(cfg, MapSource{})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 4, len(configErr.Errors))
+		require.Equal(t, "PORT", configErr.Errors[0].Key)
+		require.Equal(t, "TIMEOUT", configErr.Errors[1].Key)
+		require.Equal(t, "NAME", configErr.Errors[2].Key)
+		require.Equal(t, "BROKERS", configErr.Errors[3].Key)
+	})
+
+	t.Run("values kept by Load are validated", func(t *testing.T) {
+		type config struct {
+			Port  int    `env:"PORT" min:"1"`
+			Level string `env:"LEVEL" oneof:"debug,info"`
+		}
+		_, err := LoadWithDefaults(config{Port: 8080, Level: "trace"}, FromSources(MapSource{}))
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 1, len(configErr.Errors))
+		require.Equal(t, "LEVEL", configErr.Errors[0].Key)
+		require.ErrorIs(t, configErr.Errors[0], ErrInvalid)
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		cfg := &struct {
+			Port  int    `env:"PORT" min:"one"`
+			Level string `env:"LEVEL" validate:"email"`
+		}{}
+		err := ParseConfigFrom(cfg, MapSource{"PORT": "1", "LEVEL": "info"})
+
+		var configErr *ConfigError
+		require.True(t, errs.As(err, &configErr))
+		require.Equal(t, 2, len(configErr.Errors))
+	})
+}
+
+var errPoolSize = errs.New("min pool size must not exceed max pool size")
+
+type poolValidated struct {
+	Min int `env:"MIN"`
+	Max int `env:"MAX"`
+}
+
+func (p *poolValidated) Validate() error {
+	if p.Min > p.Max {
+		return errPoolSize
+	}
+	return nil
+}
+
+type rootValidated struct {
+	Db struct {
+		Pool poolValidated `env:"POOL"`
+	} `env:"DB"`
+	Calls *[]string
+}
+
+func (r *rootValidated) Validate() error {
+	*r.Calls = append(*r.Calls, "root")
+	return nil
+}
+
+func TestParseConfig_Validator(t *testing.T) {
+	t.Run("nested validator failure is reported with key prefix", func(t *testing.T) {
+		cfg := &rootValidated{Calls: &[]string{}}
+		err := ParseConfigFrom(cfg, MapSource{"DB_POOL_MIN": "10", "DB_POOL_MAX": "5"})
+		require.ErrorIs(t, err, errPoolSize)
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "DB_POOL", fieldErr.Key)
+		require.Equal(t, "Db.Pool", fieldErr.Field)
+		require.EqualValues(t, []string{"root"}, *cfg.Calls)
+	})
+
+	t.Run("validators are not called when fields failed to parse", func(t *testing.T) {
+		cfg := &rootValidated{Calls: &[]string{}}
+		err := ParseConfigFrom(cfg, MapSource{"DB_POOL_MIN": "x"})
+		require.Error(t, err)
+		require.Equal(t, 0, len(*cfg.Calls))
+	})
+
+	t.Run("valid config", func(t *testing.T) {
+		cfg := &rootValidated{Calls: &[]string{}}
+		err := ParseConfigFrom(cfg, MapSource{"DB_POOL_MIN": "1", "DB_POOL_MAX": "5"})
+		require.NoError(t, err)
+		require.EqualValues(t, []string{"root"}, *cfg.Calls)
+	})
+
+	t.Run("root validator failure is labeled", func(t *testing.T) {
+		cfg := &poolValidated{}
+		err := ParseConfigFrom(cfg, MapSource{"MIN": "10", "MAX": "5"})
+		require.ErrorIs(t, err, errPoolSize)
+
+		var fieldErr *FieldError
+		require.True(t, errs.As(err, &fieldErr))
+		require.Equal(t, "(root)", fieldErr.Key)
+		require.Equal(t, "(root)", fieldErr.Field)
+	})
+}
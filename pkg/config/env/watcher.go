@@ -0,0 +1,345 @@
+package env
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/errs"
+)
+
+// Watcher keeps config of type T up to date.
+// Config is reloaded when process receives one of the signals (SIGHUP by default)
+// or when one of the .env files or secret value files is modified.
+// New config is swapped in only if it's parsed and validated without errors.
+// Every reload starts from defaults of T the same way Load does.
+//
+//	w, err := env.NewWatcher[Config](env.WatchFiles(".env"))
+//	if err != nil {
+//		return err
+//	}
+//	w.Subscribe(func(u env.Update[Config]) {
+//		limiter.SetLimit(u.New.RateLimit)
+//	})
+//	go w.Run(ctx)
+type Watcher[T any] struct {
+	opts    watchOptions
+	current atomic.Pointer[T]
+
+	reloadMu    sync.Mutex // serializes reloads, so subscribers get updates in order
+	mu          sync.Mutex // guards fields below
+	subscribers map[int]func(Update[T])
+	nextID      int
+	files       map[string]fileState
+}
+
+// Update is delivered to subscribers when config changes.
+type Update[T any] struct {
+	Old     *T
+	New     *T
+	Changes []Change
+}
+
+// Change describes a field which value differs between old and new config.
+// Old and New values of secret fields are redacted.
+type Change struct {
+	Field string // Go path of the field, e.g. Db.Pool.Max
+	Key   string // env key of the field, e.g. DB_POOL_MAX
+	Old   any
+	New   any
+}
+
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	files    []string
+	signals  []os.Signal
+	interval time.Duration
+	onError  func(error)
+	parse    []Option
+}
+
+// WatchFiles sets .env files, which are read on every reload and polled for modifications.
+// Process environment takes precedence over files, earlier files take precedence over later ones.
+func WatchFiles(paths ...string) WatchOption {
+	return func(o *watchOptions) {
+		o.files = paths
+	}
+}
+
+// WatchSignals sets signals that trigger reload, SIGHUP by default.
+// Call without arguments to disable reloading on signals.
+func WatchSignals(signals ...os.Signal) WatchOption {
+	return func(o *watchOptions) {
+		o.signals = signals
+	}
+}
+
+// WatchInterval sets how often files are checked for modifications, 5 seconds by default.
+// Interval must be positive, NewWatcher returns error otherwise.
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = interval
+	}
+}
+
+// WatchErrors sets handler of reload errors that happen in Run.
+// Errors are ignored by default, current config is kept on error.
+func WatchErrors(onError func(error)) WatchOption {
+	return func(o *watchOptions) {
+		o.onError = onError
+	}
+}
+
+// WatchParseOptions sets options of the parser, e.g. KeyPrefix or Strict.
+// Sources are set by the watcher, see WatchFiles.
+func WatchParseOptions(opts ...Option) WatchOption {
+	return func(o *watchOptions) {
+		o.parse = opts
+	}
+}
+
+// NewWatcher loads the initial config and returns error if it's invalid.
+func NewWatcher[T any](opts ...WatchOption) (*Watcher[T], error) {
+	o := watchOptions{
+		signals:  []os.Signal{syscall.SIGHUP},
+		interval: 5 * time.Second,
+		onError:  func(error) {},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.interval <= 0 {
+		return nil, errs.Newf("watch interval must be positive, got %s", o.interval)
+	}
+
+	w := &Watcher[T]{
+		opts:        o,
+		subscribers: make(map[int]func(Update[T])),
+	}
+	if err := w.Reload(); err != nil {
+		return nil, errs.Wrap(err, "failed to load initial config")
+	}
+	return w, nil
+}
+
+// Current returns the latest valid config. Returned value must not be modified.
+func (w *Watcher[T]) Current() *T {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called after every reload that changed the config.
+// fn is called from the goroutine that performed the reload and must not call Reload,
+// it can subscribe and unsubscribe. Returned function unsubscribes fn.
+func (w *Watcher[T]) Subscribe(fn func(Update[T])) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// Reload re-reads process environment and files.
+// If new config is invalid, current config is kept and error is returned.
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	source := ChainSource{envSnapshot()}
+	for _, path := range w.opts.files {
+		fileSource, err := DotEnvSource(path)
+		if err != nil {
+			w.setFiles(w.statFiles(nil))
+			return err
+		}
+		source = append(source, fileSource)
+	}
+
+	cfg := new(T)
+	*cfg = defaultsOf[T]()
+	cloneNested(reflect.ValueOf(cfg).Elem())
+	o := newOptions(w.opts.parse)
+	o.sources = []Source{source}
+	o.keepValues = true
+	p, err := parseConfig(cfg, o)
+	if p != nil {
+		w.setFiles(w.statFiles(p.files))
+	}
+	if err != nil {
+		return err
+	}
+
+	old := w.current.Swap(cfg)
+	if old == nil {
+		return nil
+	}
+
+	changes := diffConfigs(o.naming, reflect.ValueOf(old).Elem(), reflect.ValueOf(cfg).Elem())
+	if len(changes) == 0 {
+		return nil
+	}
+	update := Update[T]{Old: old, New: cfg, Changes: changes}
+	for _, fn := range w.subscribersList() {
+		fn(update)
+	}
+	return nil
+}
+
+// subscribersList returns copy of subscribers, so they are called without the lock.
+func (w *Watcher[T]) subscribersList() []func(Update[T]) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	subscribers := make([]func(Update[T]), 0, len(w.subscribers))
+	for _, fn := range w.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	return subscribers
+}
+
+func (w *Watcher[T]) setFiles(files map[string]fileState) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files = files
+}
+
+// Run reloads config on signals and file modifications until ctx is done.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	if len(w.opts.signals) > 0 {
+		signal.Notify(signals, w.opts.signals...)
+		defer signal.Stop(signals)
+	}
+
+	ticker := time.NewTicker(w.opts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signals:
+		case <-ticker.C:
+			if !w.filesChanged() {
+				continue
+			}
+		}
+
+		if err := w.Reload(); err != nil {
+			w.opts.onError(err)
+		}
+	}
+}
+
+// fileState is used to detect modification of a file between polls.
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+}
+
+// statFiles remembers state of .env files and files read by the last reload: config files and secret value files.
+func (w *Watcher[T]) statFiles(valueFiles []string) map[string]fileState {
+	files := make(map[string]fileState, len(w.opts.files)+len(valueFiles))
+	for _, path := range w.opts.files {
+		files[path] = statFile(path)
+	}
+	for _, path := range valueFiles {
+		files[path] = statFile(path)
+	}
+	return files
+}
+
+func (w *Watcher[T]) filesChanged() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for path, state := range w.files {
+		if statFile(path) != state {
+			return true
+		}
+	}
+	return false
+}
+
+// envSnapshot copies process environment, so config is parsed from a consistent state.
+func envSnapshot() snapshotSource {
+	environ := os.Environ()
+	snapshot := snapshotSource{values: make(MapSource, len(environ))}
+	for _, kv := range environ {
+		key, value, _ := strings.Cut(kv, "=")
+		snapshot.values[key] = value
+	}
+	return snapshot
+}
+
+// snapshotSource is OSSource frozen at a point in time, its values are used as is like values of OSSource.
+type snapshotSource struct {
+	values MapSource
+}
+
+func (s snapshotSource) Lookup(key string) (string, bool) {
+	return s.values.Lookup(key)
+}
+
+func (s snapshotSource) Keys() []string {
+	return s.values.Keys()
+}
+
+func (s snapshotSource) Origin(string) string {
+	return "env"
+}
+
+// diffConfigs lists fields which values differ between old and new config structs.
+func diffConfigs(n naming, old, new reflect.Value) []Change {
+	var changes []Change
+	n.walkFields(old.Type(), "", "", nil, func(field reflect.StructField, key, path string) bool {
+		oldValue := valueByPath(old, path)
+		newValue := valueByPath(new, path)
+		if reflect.DeepEqual(oldValue, newValue) {
+			return true
+		}
+		if isSecret(field) {
+			oldValue, newValue = redacted, redacted
+		}
+		changes = append(changes, Change{Field: path, Key: key, Old: oldValue, New: newValue})
+		return true
+	})
+	return changes
+}
+
+// valueByPath returns value of the field at Go path like Db.Pool.Max.
+// nil is returned if one of the pointers on the path is nil.
+func valueByPath(v reflect.Value, path string) any {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(name)
+	}
+	return v.Interface()
+}
@@ -0,0 +1,223 @@
+package env
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pechorka/gostdlib/pkg/testing/require"
+)
+
+type watchedConfig struct {
+	Limits struct {
+		Rate  int `env:"RATE" default:"10" min:"1"`
+		Burst int `env:"BURST" default:"20"`
+	} `env:"WATCHER_LIMITS"`
+	Token Secret[string] `env:"WATCHER_TOKEN"`
+}
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWatcher(t *testing.T) {
+	t.Run("reload swaps config and notifies subscribers", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		writeFile(t, path, "WATCHER_LIMITS_RATE=5", time.Now())
+
+		w, err := NewWatcher[watchedConfig](WatchFiles(path))
+		require.NoError(t, err)
+		initial := w.Current()
+		require.Equal(t, 5, initial.Limits.Rate)
+		require.Equal(t, 20, initial.Limits.Burst)
+
+		var updates []Update[watchedConfig]
+		w.Subscribe(func(u Update[watchedConfig]) {
+			updates = append(updates, u)
+		})
+
+		writeFile(t, path, "WATCHER_LIMITS_RATE=50", time.Now())
+		require.NoError(t, w.Reload())
+
+		require.Equal(t, 50, w.Current().Limits.Rate)
+		require.Equal(t, 5, initial.Limits.Rate)
+		require.Equal(t, 1, len(updates))
+		require.Equal(t, initial, updates[0].Old)
+		require.Equal(t, w.Current(), updates[0].New)
+		require.EqualValues(t, []Change{{
+			Field: "Limits.Rate",
+			Key:   "WATCHER_LIMITS_RATE",
+			Old:   5,
+			New:   50,
+		}}, updates[0].Changes)
+	})
+
+	t.Run("parse options", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		writeFile(t, path, "NS_WATCHER_LIMITS_RATE=5", time.Now())
+
+		w, err := NewWatcher[watchedConfig](WatchFiles(path), WatchParseOptions(KeyPrefix("NS_")))
+		require.NoError(t, err)
+		require.Equal(t, 5, w.Current().Limits.Rate)
+
+		var changes []Change
+		w.Subscribe(func(u Update[watchedConfig]) { changes = u.Changes })
+		writeFile(t, path, "NS_WATCHER_LIMITS_RATE=6", time.Now())
+		require.NoError(t, w.Reload())
+		require.Equal(t, 1, len(changes))
+		require.Equal(t, "NS_WATCHER_LIMITS_RATE", changes[0].Key)
+	})
+
+	t.Run("changes of secrets are redacted", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		writeFile(t, path, "WATCHER_PASSWORD=old\nWATCHER_TOKEN=old", time.Now())
+
+		type secretConfig struct {
+			Password string         `env:"WATCHER_PASSWORD" secret:"true"`
+			Token    Secret[string] `env:"WATCHER_TOKEN"`
+		}
+		w, err := NewWatcher[secretConfig](WatchFiles(path))
+		require.NoError(t, err)
+
+		var changes []Change
+		w.Subscribe(func(u Update[secretConfig]) { changes = u.Changes })
+		writeFile(t, path, "WATCHER_PASSWORD=new\nWATCHER_TOKEN=new", time.Now())
+		require.NoError(t, w.Reload())
+
+		require.EqualValues(t, []Change{
+			{Field: "Password", Key: "WATCHER_PASSWORD", Old: redacted, New: redacted},
+			{Field: "Token", Key: "WATCHER_TOKEN", Old: redacted, New: redacted},
+		}, changes)
+	})
+
+	t.Run("subscribers are not notified without changes", func(t *testing.T) {
+		w, err := NewWatcher[watchedConfig]()
+		require.NoError(t, err)
+
+		called := false
+		w.Subscribe(func(Update[watchedConfig]) { called = true })
+		require.NoError(t, w.Reload())
+		require.False(t, called)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		w, err := NewWatcher[watchedConfig]()
+		require.NoError(t, err)
+
+		called := false
+		unsubscribe := w.Subscribe(func(Update[watchedConfig]) { called = true })
+		unsubscribe()
+
+		prepareEnv(t, "WATCHER_LIMITS_BURST", "30")
+		require.NoError(t, w.Reload())
+		require.Equal(t, 30, w.Current().Limits.Burst)
+		require.False(t, called)
+	})
+
+	t.Run("invalid config is not swapped", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		writeFile(t, path, "WATCHER_LIMITS_RATE=5", time.Now())
+
+		w, err := NewWatcher[watchedConfig](WatchFiles(path))
+		require.NoError(t, err)
+
+		writeFile(t, path, "WATCHER_LIMITS_RATE=0", time.Now())
+		require.ErrorIs(t, w.Reload(), ErrInvalid)
+		require.Equal(t, 5, w.Current().Limits.Rate)
+	})
+
+	t.Run("subscriber can unsubscribe itself", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".env")
+		writeFile(t, path, "WATCHER_LIMITS_RATE=5", time.Now())
+
+		w, err := NewWatcher[watchedConfig](WatchFiles(path))
+		require.NoError(t, err)
+
+		calls := 0
+		var unsubscribe func()
+		unsubscribe = w.Subscribe(func(Update[watchedConfig]) {
+			calls++
+			unsubscribe()
+		})
+
+		writeFile(t, path, "WATCHER_LIMITS_RATE=6", time.Now())
+		require.NoError(t, w.Reload())
+		writeFile(t, path, "WATCHER_LIMITS_RATE=7", time.Now())
+		require.NoError(t, w.Reload())
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("interval must be positive", func(t *testing.T) {
+		_, err := NewWatcher[watchedConfig](WatchInterval(0))
+		require.Error(t, err)
+		_, err = NewWatcher[watchedConfig](WatchInterval(-time.Second))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid initial config", func(t *testing.T) {
+		prepareEnv(t, "WATCHER_LIMITS_RATE", "x")
+
+		_, err := NewWatcher[watchedConfig]()
+		require.Error(t, err)
+	})
+
+	t.Run("run reloads on file modification", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, ".env")
+		tokenPath := filepath.Join(dir, "token")
+		past := time.Now().Add(-time.Hour)
+		writeFile(t, path, "WATCHER_TOKEN_FILE="+tokenPath, past)
+		writeFile(t, tokenPath, "old", past)
+
+		w, err := NewWatcher[watchedConfig](WatchFiles(path), WatchInterval(time.Millisecond), WatchSignals())
+		require.NoError(t, err)
+		require.Equal(t, "old", w.Current().Token.Value())
+
+		updated := make(chan Update[watchedConfig], 1)
+		w.Subscribe(func(u Update[watchedConfig]) { updated <- u })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Run(ctx)
+
+		writeFile(t, tokenPath, "new", time.Now())
+		select {
+		case u := <-updated:
+			require.Equal(t, "new", u.New.Token.Value())
+			require.Equal(t, "WATCHER_TOKEN", u.Changes[0].Key)
+		case <-time.After(5 * time.Second):
+			t.Fatal("config was not reloaded")
+		}
+		require.Equal(t, "new", w.Current().Token.Value())
+	})
+
+	t.Run("run reloads on config file modification", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		writeFile(t, path, `{"watcher_limits": {"rate": 5}}`, time.Now().Add(-time.Hour))
+
+		w, err := NewWatcher[watchedConfig](
+			WatchParseOptions(FromFiles(path)), WatchInterval(time.Millisecond), WatchSignals(),
+		)
+		require.NoError(t, err)
+		require.Equal(t, 5, w.Current().Limits.Rate)
+
+		updated := make(chan Update[watchedConfig], 1)
+		w.Subscribe(func(u Update[watchedConfig]) { updated <- u })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go w.Run(ctx)
+
+		writeFile(t, path, `{"watcher_limits": {"rate": 6}}`, time.Now())
+		select {
+		case u := <-updated:
+			require.Equal(t, 6, u.New.Limits.Rate)
+		case <-time.After(5 * time.Second):
+			t.Fatal("config was not reloaded")
+		}
+	})
+}
@@ -10,24 +10,7 @@ import (
 )
 
 // TODO:
-// 1) allow dst struct to have nested structs
-// For example, repos and services can be nested in the App struct
-//
-//		type Repos struct {
-//			UserRepo *UserRepo
-//			PostRepo *PostRepo
-//		}
-//
-//	 type Services struct {
-//			UserService *UserService
-//			PostService *PostService
-//		}
-//	 type App struct {
-//			Repos *Repos
-//			Services *Services
-//		}
-//
-// 2) ability to provide dependency sub-structs
+// 1) ability to provide dependency sub-structs
 // For example, userRepo and postRepo are provided in the Repos struct,
 // but userService and postService are dependent on them, not Repos
 /*
@@ -87,6 +70,15 @@ import (
 //			MyService *MyService
 //		}
 //		provider.Provide(&Deps{})
+//
+// Fields of nested structs and pointers to structs without their own provider are filled too,
+// nil pointers are allocated. `di:"nested"` tag fills the field this way even if its type has a provider:
+//
+//	type App struct {
+//		Repos    *Repos               // Repos.UserRepo, Repos.PostRepo are provided
+//		Services Services             // Services.UserService is provided
+//		Config   *Config `di:"nested"` // filled field by field, provider of *Config isn't called
+//	}
 type Provider struct {
 	allProvidedTypes map[reflect.Type]providerInfo
 	resolvedTypes    map[reflect.Type]reflect.Value
@@ -207,13 +199,42 @@ func (c *Provider) Provide(dst any) error {
 	if dstType.Kind() != reflect.Ptr || dstType.Elem().Kind() != reflect.Struct {
 		return errs.Errorf("destination must be a pointer to a struct, got %s", dstType.Kind())
 	}
-	dstValue := reflect.ValueOf(dst).Elem()
-	for i := 0; i < dstValue.NumField(); i++ {
-		field := dstValue.Field(i)
-		fieldType := dstType.Elem().Field(i)
+	return c.provideStruct(reflect.ValueOf(dst).Elem(), "", nil)
+}
+
+// provideStruct fills fields of struct v, path is the path of v in the destination, e.g. Repos.
+// visited holds types of nested structs on the path to detect recursive types like `type Node struct { Next *Node }`.
+func (c *Provider) provideStruct(v reflect.Value, path string, visited []reflect.Type) error {
+	if slices.Contains(visited, v.Type()) {
+		return errs.Errorf("failed to resolve field %s: recursive nested type %s", path, v.Type())
+	}
+	visited = append(visited, v.Type())
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		fieldType := v.Type().Field(i)
+		fieldPath := fieldType.Name
+		if path != "" {
+			fieldPath = path + "." + fieldType.Name
+		}
+
+		if !fieldType.IsExported() {
+			return errs.Errorf("failed to resolve field %s: field is unexported", fieldPath)
+		}
+
+		if fieldType.Tag.Get("di") == "nested" && !isStructOrPointer(fieldType.Type) {
+			return errs.Errorf("failed to resolve field %s: nested field must be a struct or a pointer to a struct, got %s", fieldPath, fieldType.Type)
+		}
+		if fieldType.Tag.Get("di") == "nested" || c.isNested(fieldType.Type) {
+			if err := c.provideNested(field, fieldPath, visited); err != nil {
+				return err
+			}
+			continue
+		}
+
 		fieldValue, err := c.resolve(fieldType.Type)
 		if err != nil {
-			return errs.Wrapf(err, "failed to resolve field %s", fieldType.Name)
+			return errs.Wrapf(err, "failed to resolve field %s", fieldPath)
 		}
 		field.Set(fieldValue)
 	}
@@ -221,6 +242,50 @@ func (c *Provider) Provide(dst any) error {
 	return nil
 }
 
+// isNested reports whether t is a struct or a pointer to a struct without provider, which fields should be filled.
+// Structs with unexported fields like time.Time can't be filled, so they need a provider.
+func (c *Provider) isNested(t reflect.Type) bool {
+	if _, ok := c.allProvidedTypes[t]; ok {
+		return false
+	}
+	if !isStructOrPointer(t) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+func isStructOrPointer(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// provideNested fills nested struct or pointer to struct, allocating nil pointer.
+func (c *Provider) provideNested(v reflect.Value, path string, visited []reflect.Type) error {
+	if v.Kind() != reflect.Ptr {
+		return c.provideStruct(v, path, visited)
+	}
+
+	nested := v
+	if v.IsNil() {
+		nested = reflect.New(v.Type().Elem())
+	}
+	if err := c.provideStruct(nested.Elem(), path, visited); err != nil {
+		return err
+	}
+	v.Set(nested)
+	return nil
+}
+
 func (c *Provider) resolve(fieldType reflect.Type) (reflect.Value, error) {
 	if value, ok := c.resolvedTypes[fieldType]; ok {
 		return value, nil
@@ -193,3 +193,120 @@ func TestComplexDependencies(t *testing.T) {
 	require.Equal(t, "postgresql://localhost", dst.DB.URL)
 	require.True(t, dst.Svc.Active)
 }
+
+func TestProvider_ProvideNested(t *testing.T) {
+	type UserRepo struct{ Name string }
+	type PostRepo struct{ Name string }
+	type UserService struct{ Repo *UserRepo }
+	type Repos struct {
+		UserRepo *UserRepo
+		PostRepo *PostRepo
+	}
+	type Services struct {
+		UserService *UserService
+	}
+	type App struct {
+		Repos    *Repos
+		Services Services
+		Name     string
+	}
+
+	newProvider := func(t *testing.T) *Provider {
+		t.Helper()
+		provider, err := NewProvider(
+			func() *UserRepo { return &UserRepo{Name: "users"} },
+			func() *PostRepo { return &PostRepo{Name: "posts"} },
+			func(repo *UserRepo) *UserService { return &UserService{Repo: repo} },
+			func() string { return "app" },
+		)
+		require.NoError(t, err)
+		return provider
+	}
+
+	t.Run("nested structs and pointers are filled", func(t *testing.T) {
+		app := &App{}
+		err := newProvider(t).Provide(app)
+		require.NoError(t, err)
+
+		require.Equal(t, "users", app.Repos.UserRepo.Name)
+		require.Equal(t, "posts", app.Repos.PostRepo.Name)
+		require.Equal(t, app.Repos.UserRepo, app.Services.UserService.Repo)
+		require.Equal(t, "app", app.Name)
+	})
+
+	t.Run("existing pointer is reused", func(t *testing.T) {
+		repos := &Repos{}
+		app := &App{Repos: repos}
+		err := newProvider(t).Provide(app)
+		require.NoError(t, err)
+
+		require.True(t, app.Repos == repos)
+		require.Equal(t, "users", repos.UserRepo.Name)
+	})
+
+	t.Run("struct with provider is not descended into", func(t *testing.T) {
+		provider, err := NewProvider(func() *Repos { return &Repos{} })
+		require.NoError(t, err)
+
+		dst := &struct{ Repos *Repos }{}
+		require.NoError(t, provider.Provide(dst))
+		require.True(t, dst.Repos.UserRepo == nil)
+	})
+
+	t.Run("tag descends into struct with provider", func(t *testing.T) {
+		provider, err := NewProvider(
+			func() *Repos { return &Repos{} },
+			func() *UserRepo { return &UserRepo{Name: "users"} },
+			func() *PostRepo { return &PostRepo{Name: "posts"} },
+		)
+		require.NoError(t, err)
+
+		dst := &struct {
+			Repos *Repos `di:"nested"`
+		}{}
+		require.NoError(t, provider.Provide(dst))
+		require.Equal(t, "users", dst.Repos.UserRepo.Name)
+	})
+
+	t.Run("error names full field path", func(t *testing.T) {
+		provider, err := NewProvider(func() *UserRepo { return &UserRepo{} })
+		require.NoError(t, err)
+
+		err = provider.Provide(&App{})
+		require.Error(t, err)
+		require.Equal(t, "failed to resolve field Repos.PostRepo.Name: no provider found for type string", err.Error())
+	})
+
+	t.Run("nested field must be a struct", func(t *testing.T) {
+		provider, err := NewProvider(func() string { return "" })
+		require.NoError(t, err)
+
+		err = provider.Provide(&struct {
+			S string `di:"nested"`
+		}{})
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "field S"))
+	})
+
+	t.Run("recursive type", func(t *testing.T) {
+		type Node struct {
+			Next *Node
+		}
+		provider, err := NewProvider(func() string { return "" })
+		require.NoError(t, err)
+
+		err = provider.Provide(&struct{ Root Node }{})
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "Root.Next"))
+	})
+
+	t.Run("struct with unexported fields needs provider", func(t *testing.T) {
+		type Clock struct{ now func() int }
+		provider, err := NewProvider(func() string { return "" })
+		require.NoError(t, err)
+
+		err = provider.Provide(&struct{ Clock Clock }{})
+		require.Error(t, err)
+		require.True(t, strings.Contains(err.Error(), "no provider found for type di.Clock"))
+	})
+}